@@ -0,0 +1,98 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxArgsFileDepth is how deeply `@path` arguments may be nested
+// inside of other args files before expansion gives up.
+const maxArgsFileDepth = 16
+
+// expandArgsFiles replaces every `@path` argument preceding the first
+// `--` with the words read from path.  Args files may themselves
+// contain `@path` arguments, up to maxArgsFileDepth levels deep.
+func expandArgsFiles(args []string) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+		if !isArgsFile(arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+		words, err := readArgsFile(arg[1:], nil)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, words...)
+	}
+	return expanded, nil
+}
+
+func isArgsFile(arg string) bool {
+	return len(arg) > 1 && arg[0] == '@'
+}
+
+func readArgsFile(path string, stack []string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("args file cycle detected: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+	if len(stack) >= maxArgsFileDepth {
+		return nil, fmt.Errorf("args files nested more than %d deep at %s", maxArgsFileDepth, path)
+	}
+	stack = append(stack, abs)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	words, err := splitWords(string(content))
+	if err != nil {
+		if we, ok := err.(*wordsError); ok {
+			return nil, fmt.Errorf("%s:%d: %s", path, we.line, we.msg)
+		}
+		return nil, err
+	}
+
+	args := make([]string, 0, len(words))
+	for _, w := range words {
+		if !isArgsFile(w.text) {
+			args = append(args, w.text)
+			continue
+		}
+		nested, err := readArgsFile(w.text[1:], stack)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, w.line, err)
+		}
+		args = append(args, nested...)
+	}
+	return args, nil
+}
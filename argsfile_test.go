@@ -0,0 +1,76 @@
+package optigo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeArgsFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestArgsFileExpansion(t *testing.T) {
+	dir := t.TempDir()
+	nested := writeArgsFile(t, dir, "nested", "--many 'c d'\n")
+	top := writeArgsFile(t, dir, "top", `
+# comment line
+--many a
+--many "b \"quoted\""
+@`+nested+`
+`)
+
+	var many []string
+	op := NewDirectAssignParser(map[string]interface{}{
+		"many=s@": &many,
+	})
+	op.ExpandArgsFiles = true
+
+	if err := op.ProcessAll([]string{"@" + top, "extra", "--", "@" + top}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(many, []string{"a", `b "quoted"`, "c d"}) {
+		t.Errorf("unexpected many: %#v", many)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"extra", "@" + top}) {
+		t.Errorf("unexpected args: %#v", op.Args)
+	}
+}
+
+func TestArgsFileDisabled(t *testing.T) {
+	op := NewParser([]string{"v|verbose+"})
+	if err := op.ProcessAll([]string{"@nonexistent"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"@nonexistent"}) {
+		t.Errorf("unexpected args: %#v", op.Args)
+	}
+}
+
+func TestArgsFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	unterminated := writeArgsFile(t, dir, "unterminated", "-v\n-v 'oops\n")
+	missing := writeArgsFile(t, dir, "missing", "-v\n\n@"+filepath.Join(dir, "nope")+"\n")
+	cycleA := filepath.Join(dir, "a")
+	cycleB := writeArgsFile(t, dir, "b", "@"+cycleA)
+	writeArgsFile(t, dir, "a", "@"+cycleB)
+
+	for path, want := range map[string]string{
+		unterminated: unterminated + ":2: unterminated single quote",
+		missing:      missing + ":3: ",
+		cycleA:       "cycle detected",
+	} {
+		op := NewParser([]string{"v|verbose+"})
+		op.ExpandArgsFiles = true
+		err := op.ProcessAll([]string{"@" + path})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Callback pairs option aliases with a typed callback function.  A
+// Callback is created with one of the builder functions like Int or
+// StringMap and passed to NewCallbackParser.
+type Callback struct {
+	spec   string
+	suffix string
+	fn     func(value interface{}) error
+}
+
+func typed[T any](spec, suffix string, fn func(T) error) Callback {
+	return Callback{spec, suffix, func(value interface{}) error {
+		return fn(value.(T))
+	}}
+}
+
+func typedMap[T any](spec, suffix string, fn func(string, T) error) Callback {
+	return Callback{spec, suffix, func(value interface{}) error {
+		kv := value.(keyVal)
+		return fn(kv.key.(string), kv.val.(T))
+	}}
+}
+
+// Bool creates a Callback for a flag like "b|bool".  The callback
+// is called with true each time the flag is seen.
+func Bool(spec string, fn func(bool) error) Callback {
+	return typed(spec, "", fn)
+}
+
+// Counter creates a Callback for a repeatable flag like "v|verbose".
+// The callback is called each time the flag is seen.
+func Counter(spec string, fn func() error) Callback {
+	return typed(spec, "+", func(bool) error { return fn() })
+}
+
+// Int creates a Callback for an option like "i|int" that takes an
+// integer value.
+func Int(spec string, fn func(int64) error) Callback {
+	return typed(spec, "=i", fn)
+}
+
+// Float creates a Callback for an option like "f|float" that takes
+// a floating point value.
+func Float(spec string, fn func(float64) error) Callback {
+	return typed(spec, "=f", fn)
+}
+
+// String creates a Callback for an option like "s|str" that takes a
+// string value.
+func String(spec string, fn func(string) error) Callback {
+	return typed(spec, "=s", fn)
+}
+
+// IntMap creates a Callback for an option like "o|opt" that takes
+// key=value arguments with integer values.
+func IntMap(spec string, fn func(key string, value int64) error) Callback {
+	return typedMap(spec, "=i%", fn)
+}
+
+// FloatMap creates a Callback for an option like "o|opt" that takes
+// key=value arguments with floating point values.
+func FloatMap(spec string, fn func(key string, value float64) error) Callback {
+	return typedMap(spec, "=f%", fn)
+}
+
+// StringMap creates a Callback for an option like "o|opt" that takes
+// key=value arguments with string values.
+func StringMap(spec string, fn func(key, value string) error) Callback {
+	return typedMap(spec, "=s%", fn)
+}
+
+// NewCallbackParser generates an OptionParser object from the typed
+// callbacks passed in.  The callback specs must only contain option
+// aliases, the value type is determined by the builder used.  Decrement
+// aliases like the -q in "v|-q" are not allowed.
+func NewCallbackParser(callbacks ...Callback) OptionParser {
+	actions := make(actions)
+	for _, cb := range callbacks {
+		if cb.spec == "" || strings.ContainsAny(cb.spec, "=+@%!{}[].") {
+			panic(fmt.Errorf("invalid callback spec %q: only option aliases are allowed", cb.spec))
+		}
+		for _, alias := range strings.Split(cb.spec, "|") {
+			if strings.HasPrefix(alias, "-") {
+				// the callback could not tell a decrement from an increment
+				panic(fmt.Errorf("invalid callback spec %q: decrement alias %s is not allowed", cb.spec, alias))
+			}
+		}
+		if err := parseAction(cb.spec+cb.suffix, cb.fn, actions); err != nil {
+			panic(err)
+		}
+	}
+	return OptionParser{actions: actions}
+}
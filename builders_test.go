@@ -0,0 +1,66 @@
+package optigo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCallbackParser(t *testing.T) {
+	var b bool
+	var count int
+	var i int64
+	var f float64
+	var s string
+	im := make(map[string]int64)
+	fm := make(map[string]float64)
+	sm := make(map[string]string)
+
+	op := NewCallbackParser(
+		Bool("b", func(v bool) error { b = v; return nil }),
+		Counter("v|verbose", func() error { count++; return nil }),
+		Int("i|int", func(v int64) error { i = v; return nil }),
+		Float("f|float", func(v float64) error { f = v; return nil }),
+		String("s|str", func(v string) error { s = v; return nil }),
+		IntMap("im", func(k string, v int64) error { im[k] = v; return nil }),
+		FloatMap("fm", func(k string, v float64) error { fm[k] = v; return nil }),
+		StringMap("sm", func(k, v string) error { sm[k] = v; return nil }),
+	)
+
+	args := []string{"-b", "-v", "--verbose", "-i", "42", "--float=1.5", "-s", "hey", "--im", "a=1", "--fm", "b=2.5", "--sm", "c=d"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+
+	if !b || count != 2 || i != 42 || f != 1.5 || s != "hey" {
+		t.Errorf("unexpected values: %v %v %v %v %v", b, count, i, f, s)
+	}
+	if !reflect.DeepEqual(im, map[string]int64{"a": 1}) || !reflect.DeepEqual(fm, map[string]float64{"b": 2.5}) || !reflect.DeepEqual(sm, map[string]string{"c": "d"}) {
+		t.Errorf("unexpected maps: %v %v %v", im, fm, sm)
+	}
+}
+
+func TestBogusCallbackSpec(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fail()
+		}
+	}()
+
+	// the type is implied by the builder, so it cannot be in the spec
+	NewCallbackParser(
+		Int("i|int=s", func(int64) error { return nil }),
+	)
+}
+
+func TestCallbackDecrementAlias(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fail()
+		}
+	}()
+
+	// the callback is not told which way to count
+	NewCallbackParser(
+		Counter("v|-q", func() error { return nil }),
+	)
+}
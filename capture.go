@@ -0,0 +1,39 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import "fmt"
+
+// capture appends the args swallowed by a capture option like
+// "exec...;" to vals, returning them along with the number of args
+// used including the terminator.  Options without a terminator, like
+// "run...", capture the rest of args.  Every arg is captured as is,
+// including `--` and anything that looks like an option.
+func (o *option) capture(vals, args []string) ([]string, int, error) {
+	vals = append(make([]string, 0, len(vals)+len(args)), vals...)
+	if o.term == "" {
+		return append(vals, args...), len(args), nil
+	}
+	for i, arg := range args {
+		if arg == o.term {
+			return append(vals, args[:i]...), i + 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("missing terminator %q for option --%s", o.term, o.name)
+}
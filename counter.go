@@ -0,0 +1,83 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parseLimits parses the limits of a counter spec like "v+{0,3}".
+// Either limit may be left out, as in "+{,3}" or "+{-2,}".
+func parseLimits(limits string) (int64, int64, error) {
+	lo, hi, ok := strings.Cut(limits, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("counter limits must be {MIN,MAX}, {MIN,} or {,MAX}")
+	}
+	min, max := int64(math.MinInt64), int64(math.MaxInt64)
+	var err error
+	if lo != "" {
+		if min, err = strconv.ParseInt(lo, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid counter minimum %q", lo)
+		}
+	}
+	if hi != "" {
+		if max, err = strconv.ParseInt(hi, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid counter maximum %q", hi)
+		}
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("counter minimum %d is greater than the maximum %d", min, max)
+	}
+	return min, max, nil
+}
+
+// decrements reports whether alias lowers the counter rather than
+// raising it, like the -q in "v|verbose|-q+".
+func (o *option) decrements(alias string) bool {
+	for _, decr := range o.decr {
+		if decr == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the new value of the counter val after an occurrence
+// with value: true increments, false decrements and an integer sets
+// the counter.  The result is clamped to the option limits and keeps
+// the type of val.
+func (o *option) count(val reflect.Value, value interface{}) reflect.Value {
+	n := val.Int()
+	switch v := value.(type) {
+	case bool:
+		if v {
+			n++
+		} else {
+			n--
+		}
+	case int64:
+		n = v
+	}
+	n = max(o.minCount, min(n, o.maxCount))
+	return reflect.ValueOf(n).Convert(val.Type())
+}
@@ -0,0 +1,89 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+)
+
+// applyEnv sets any options that have not been seen from the
+// environment variable named by OptionMeta.Env, if it is set.  It runs
+// at the end of ProcessAll and ProcessSome, so arguments always take
+// precedence over the environment.  Flags are set when the variable
+// holds a true value, counters are set to its integer value, which must
+// not be negative, as if given as --name=N, callback counters are called
+// that many times, and
+// options taking several values, like "cmd..." or "rgb=i{3}", split
+// it into words with SplitArgs.
+func (o *OptionParser) applyEnv() error {
+	for _, info := range o.Options() {
+		if info.Env == "" || o.seen[info.Name] {
+			continue
+		}
+		val, ok := os.LookupEnv(info.Env)
+		if !ok {
+			continue
+		}
+		opt, _ := o.findOption(info.Name)
+		occ := Occurrence{Alias: "$" + info.Env, Name: opt.name, Raw: val, Value: true, Index: -1}
+		count := int64(1)
+		var err error
+		if opt.action == atINCREMENT {
+			if count, err = strconv.ParseInt(val, 10, 64); err == nil && count < 0 {
+				err = fmt.Errorf("counter must not be negative")
+			}
+			if opt.dest.Kind() != reflect.Func {
+				occ.Value, count = count, 1
+			}
+		} else if opt.unary {
+			var set bool
+			if set, err = strconv.ParseBool(val); err == nil && !set {
+				count = 0
+			}
+		} else if opt.action == atCAPTURE {
+			occ.Value, err = SplitArgs(val)
+		} else if opt.takesValues() {
+			var vals []string
+			if vals, err = SplitArgs(val); err == nil {
+				occ.Value, err = o.parseValues(opt, vals)
+			}
+		} else {
+			occ.Value, err = o.parseValue(opt, val)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for environment variable %s: %s", info.Env, err)
+		}
+		if count == 0 {
+			continue
+		}
+		if err := o.setParsedOption(opt, occ); err != nil {
+			return err
+		}
+		// further calls of a callback counter are not new occurrences
+		for i := int64(1); i < count; i++ {
+			if err := o.applyOccurrence(opt, occ); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package optigo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvOptions(t *testing.T) {
+	t.Setenv("TEST_NAME", "env")
+	t.Setenv("TEST_VERBOSE", "2")
+	t.Setenv("TEST_FLAG", "true")
+	t.Setenv("TEST_OFF", "0")
+
+	op := NewParser([]string{"n|name=s", "v|verbose+", "f|flag", "o|off", "u|user=s"})
+	op.Describe("name", OptionMeta{Env: "TEST_NAME"})
+	op.Describe("verbose", OptionMeta{Env: "TEST_VERBOSE"})
+	op.Describe("flag", OptionMeta{Env: "TEST_FLAG"})
+	op.Describe("off", OptionMeta{Env: "TEST_OFF"})
+	op.Describe("user", OptionMeta{Env: "TEST_USER", Required: true})
+
+	if err := op.ProcessAll([]string{"-u", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name":    "env",
+		"verbose": int64(2),
+		"flag":    true,
+		"user":    "bob",
+	}
+	if !reflect.DeepEqual(op.Results, expected) {
+		t.Errorf("unexpected results: %#v", op.Results)
+	}
+
+	// arguments take precedence over the environment
+	op.Reset()
+	if err := op.ProcessAll([]string{"-u", "bob", "-n", "arg"}); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["name"] != "arg" {
+		t.Errorf("unexpected name: %v", op.Results["name"])
+	}
+
+	// required options can be given in the environment
+	t.Setenv("TEST_USER", "alice")
+	op.Reset()
+	if err := op.ProcessAll(nil); err != nil || op.Results["user"] != "alice" {
+		t.Errorf("unexpected user: %v %v", op.Results["user"], err)
+	}
+
+	t.Setenv("TEST_VERBOSE", "lots")
+	op.Reset()
+	if err := op.ProcessAll(nil); err == nil {
+		t.Errorf("expected error for invalid environment variable")
+	}
+}
+
+func TestEnvCounter(t *testing.T) {
+	t.Setenv("TEST_VERBOSE", "20000000")
+	var verbose int
+	op := NewDirectAssignParser(map[string]interface{}{"v|verbose+{0,3}": &verbose})
+	op.Describe("verbose", OptionMeta{Env: "TEST_VERBOSE"})
+	if err := op.ProcessAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	if verbose != 3 || len(op.Occurrences) != 1 {
+		t.Errorf("unexpected counter %d with %d occurrences", verbose, len(op.Occurrences))
+	}
+
+	t.Setenv("TEST_VERBOSE", "-1")
+	op.Reset()
+	if err := op.ProcessAll(nil); err == nil {
+		t.Errorf("expected error for negative counter")
+	}
+
+	t.Setenv("TEST_VERBOSE", "3")
+	calls := 0
+	op = NewDirectAssignParser(map[string]interface{}{"v|verbose+": func() { calls++ }})
+	op.Describe("verbose", OptionMeta{Env: "TEST_VERBOSE"})
+	if err := op.ProcessAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(op.Occurrences) != 1 {
+		t.Errorf("unexpected %d calls with %d occurrences", calls, len(op.Occurrences))
+	}
+}
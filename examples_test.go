@@ -1,9 +1,13 @@
 package optigo
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 	"sort"
+	"strings"
 )
 
 func fmtMap(m interface{}) string {
@@ -139,7 +143,7 @@ func ExampleNewParser() {
 	// fltopt: map[string]float64{"abc":123, "key":1.23}
 }
 
-func ExampleNewParser_NoSpace() {
+func ExampleNewParser_noSpace() {
 	// Note that all values will be stored in OptionParser.Results after a Process function
 	// is called.  The Result key will be stored as the last alias.
 	op := NewParser([]string{
@@ -341,8 +345,9 @@ func ExampleNewDirectAssignParser() {
 func ExampleNewDirectAssignParser_callbacks() {
 
 	usage := func() {
-		fmt.Println(`
+		fmt.Print(`
 Usage: <appname> --help ...
+
 `)
 	}
 
@@ -451,3 +456,495 @@ func ExampleOptionParser_ProcessSome() {
 	// verbose: 1
 	// unparsed args: [--bogus extra]
 }
+
+func ExampleOptionParser_ProcessString() {
+	op := NewParser([]string{
+		"n|name=s",
+		"t|tag=s@",
+	})
+
+	if err := op.ProcessString(`--name 'John Smith' -t "a b" -t c extra`); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("name: %s\n", op.Results["name"])
+	fmt.Printf("tag: %#v\n", op.Results["tag"])
+	fmt.Printf("unparsed args: %v\n", op.Args)
+
+	// Output:
+	// name: John Smith
+	// tag: []string{"a b", "c"}
+	// unparsed args: [extra]
+}
+
+func ExampleOptionParser_RequireOrder() {
+	op := NewParser([]string{
+		"v|verbose+",
+	})
+	op.RequireOrder = true
+
+	args := []string{
+		"-v",
+		"run",
+		"cmd",
+		"--flag-for-cmd",
+		"-v",
+	}
+
+	// processing stops at "run", so the unknown --flag-for-cmd is not an error
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("verbose: %d\n", op.Results["verbose"])
+	fmt.Printf("unparsed args: %v\n", op.Args)
+
+	// Output:
+	// verbose: 1
+	// unparsed args: [run cmd --flag-for-cmd -v]
+}
+
+func ExampleOptionParser_Positional() {
+	op := NewParser([]string{
+		"n|name=s",
+	})
+
+	// record which --name was in effect for each positional argument
+	op.Positional = func(arg string) error {
+		fmt.Printf("%s: %v\n", arg, op.Results["name"])
+		return nil
+	}
+
+	args := []string{
+		"a",
+		"--name", "x",
+		"b",
+		"c",
+		"--name", "y",
+		"d",
+	}
+
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("unparsed args: %v\n", op.Args)
+
+	// Output:
+	// a: <nil>
+	// b: x
+	// c: x
+	// d: y
+	// unparsed args: []
+}
+
+func ExampleErrStop() {
+	port := int64(80)
+	op := NewDirectAssignParser(map[string]interface{}{
+		"h|help": func() error {
+			fmt.Println("Usage: <appname> [--port PORT] ...")
+			return ErrStop
+		},
+		"p|port=i": func(value int64) error {
+			if value < 1 || value > 65535 {
+				return fmt.Errorf("%d is out of range", value)
+			}
+			port = value
+			return nil
+		},
+	})
+
+	if err := op.ProcessAll([]string{"--port", "8080", "--help", "extra"}); errors.Is(err, ErrStop) {
+		fmt.Printf("stopped, port: %d, unparsed args: %v\n", port, op.Args)
+	}
+
+	if err := op.ProcessAll([]string{"--port", "0"}); err != nil {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// Usage: <appname> [--port PORT] ...
+	// stopped, port: 8080, unparsed args: [extra]
+	// option --port: 0 is out of range
+}
+
+func ExampleNewCallbackParser() {
+	labels := make(map[string]string)
+	op := NewCallbackParser(
+		Int("p|port", func(port int64) error {
+			fmt.Printf("port: %d\n", port)
+			return nil
+		}),
+		StringMap("l|label", func(key, value string) error {
+			labels[key] = value
+			return nil
+		}),
+	)
+
+	if err := op.ProcessAll([]string{"--port", "8080", "-l", "env=prod"}); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("labels: %v\n", labels)
+
+	// Output:
+	// port: 8080
+	// labels: map[env:prod]
+}
+
+func ExampleOptionParser_Occurrences() {
+	op := NewParser([]string{
+		"n|name=s",
+		"o|or",
+		"t|type=s",
+	})
+
+	args := []string{
+		"src",
+		"-n", "*.go",
+		"--or",
+		"--type=d",
+	}
+
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	for _, occ := range op.Occurrences {
+		fmt.Printf("%d: alias=%q name=%q raw=%q value=%v\n", occ.Index, occ.Alias, occ.Name, occ.Raw, occ.Value)
+	}
+
+	// Output:
+	// 0: alias="" name="" raw="src" value=<nil>
+	// 1: alias="-n" name="name" raw="*.go" value=*.go
+	// 3: alias="--or" name="or" raw="" value=true
+	// 4: alias="--type" name="type" raw="d" value=d
+}
+
+func ExampleREPL() {
+	greet := NewParser([]string{
+		"n|name=s",
+		"l|loud",
+	})
+	greet.Describe("name", OptionMeta{Usage: "who to greet", Default: "world"})
+
+	repl := REPL{
+		Prompt: "> ",
+		Commands: map[string]*Command{
+			"greet": {
+				Summary: "print a greeting",
+				Parser:  &greet,
+				Run: func(op *OptionParser, out io.Writer) error {
+					name, ok := Get[string](op, "name")
+					if !ok {
+						name = "world"
+					}
+					greeting := "hello " + name
+					if loud, _ := Get[bool](op, "loud"); loud {
+						greeting = strings.ToUpper(greeting)
+					}
+					fmt.Fprintln(out, greeting)
+					return nil
+				},
+			},
+		},
+	}
+
+	input := strings.NewReader(`greet --name 'Jane Doe' --loud
+greet
+greet --bogus
+help greet
+exit
+`)
+
+	if err := repl.Run(input, os.Stdout); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// > HELLO JANE DOE
+	// > hello world
+	// > greet: Unknown option: --bogus
+	// > greet: print a greeting
+	//   -l, --loud
+	//   -n, --name STRING  who to greet (default: world)
+	// >
+}
+
+func ExampleOptionParser_WriteMan() {
+	op := NewParser([]string{
+		"v|verbose+",
+		"c|config=s",
+	})
+	op.Describe("verbose", OptionMeta{Usage: "Increase logging output."})
+	op.Describe("config", OptionMeta{Usage: "Read settings from FILE.", Env: "MYTOOL_CONFIG", Default: "~/.mytool"})
+
+	add := NewParser([]string{"f|force"})
+	add.Describe("force", OptionMeta{Usage: "Overwrite existing items."})
+
+	err := op.WriteMan(os.Stdout, Doc{
+		Name:        "mytool",
+		Date:        "2015-12-16",
+		Summary:     "manage things",
+		Description: "Manage all of the things.\n\nThings are stored in the config.",
+		Files:       map[string]string{"~/.mytool": "Default configuration."},
+		Commands: map[string]*Command{
+			"add": {Summary: "Add a thing.", Parser: &add},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// .TH "MYTOOL" 1 "2015\-12\-16"
+	// .SH NAME
+	// mytool \- manage things
+	// .SH SYNOPSIS
+	// mytool [OPTIONS] COMMAND [ARGS]
+	// .SH DESCRIPTION
+	// Manage all of the things.
+	// .PP
+	// Things are stored in the config.
+	// .SH OPTIONS
+	// .TP
+	// \fB\-c\fR, \fB\-\-config\fR \fISTRING\fR
+	// Read settings from FILE. (default: ~/.mytool)
+	// .TP
+	// \fB\-v\fR, \fB\-\-verbose\fR
+	// Increase logging output.
+	// .SH SUBCOMMANDS
+	// .SS add
+	// Add a thing.
+	// .TP
+	// \fB\-f\fR, \fB\-\-force\fR
+	// Overwrite existing items.
+	// .SH ENVIRONMENT
+	// .TP
+	// .B MYTOOL_CONFIG
+	// Used for \-\-config when it is not given.
+	// .SH FILES
+	// .TP
+	// .I ~/.mytool
+	// Default configuration.
+}
+
+func ExampleNewParser_mapList() {
+	op := NewParser([]string{
+		// Allow for many --label key=string, collecting repeated keys
+		"l|label=s%@",
+	})
+
+	args := []string{
+		"--label", "env=a",
+		"-l", "env=b",
+		"--label", "team=x",
+	}
+
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("label: %s\n", fmtMap(op.Results["label"]))
+
+	// Output:
+	// label: map[string][]string{"env":[]string{"a", "b"}, "team":[]string{"x"}}
+}
+
+func ExampleNewDirectAssignParser_typedMapKeys() {
+	weights := make(map[int]float32)
+	ports := make(map[string][]int64)
+
+	op := NewDirectAssignParser(map[string]interface{}{
+		// Allow for many --weight int=float
+		"w|weight=f{i}": &weights,
+
+		// Allow for many --port name=int, collecting repeated names
+		"p|port=i{s}@": &ports,
+	})
+
+	args := []string{
+		"--weight", "3=0.5",
+		"-w", "1=0.25",
+		"--port", "http=80",
+		"--port", "http=8080",
+	}
+
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("weight: %v\n", weights)
+	fmt.Printf("port: %v\n", ports)
+	fmt.Printf("args: %v\n", op.ToArgs())
+
+	// Output:
+	// weight: map[1:0.25 3:0.5]
+	// port: map[http:[80 8080]]
+	// args: [--port http=80 --port http=8080 --weight 1=0.25 --weight 3=0.5]
+}
+
+func ExampleNewParser_nested() {
+	op := NewParser([]string{
+		// Allow for many --set path=value, building nested maps from
+		// the dotted paths and inferring the value types
+		"set=*{.}",
+	})
+
+	args := []string{
+		"--set", "db.pool.size=10",
+		"--set", "db.host=x",
+		"--set", "db.ratio=0.5",
+		"--set", "debug=true",
+	}
+
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("set: %#v\n", op.Results["set"].(map[string]interface{})["db"].(map[string]interface{})["pool"])
+	fmt.Printf("args: %v\n", op.ToArgs())
+
+	if err := op.ProcessAll([]string{"--set", "db.host.port=1"}); err != nil {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// set: map[string]interface {}{"size":10}
+	// args: [--set db.host=x --set db.pool.size=10 --set db.ratio=0.5 --set debug=true]
+	// option --set: cannot set db.host.port, db.host already holds the value x
+}
+
+func ExampleNewParser_repeatCount() {
+	op := NewParser([]string{
+		// Each --move consumes exactly two floats
+		"m|move=f{2}",
+		// Each --color consumes three integers, collecting one slice
+		// per occurrence
+		"c|color=i{3}@",
+		// --files takes one or more strings up to the next option
+		"files=s{1,}",
+	})
+
+	args := []string{"--move", "1.5", "-2", "-c", "255", "0", "0", "-c", "0", "0", "255", "--files", "a", "b", "--move", "0", "3"}
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("move: %v\n", op.Results["move"])
+	fmt.Printf("color: %v\n", op.Results["color"])
+	fmt.Printf("files: %v\n", op.Results["files"])
+	op.WriteHelp(os.Stdout)
+
+	// Output:
+	// move: [0 3]
+	// color: [[255 0 0] [0 0 255]]
+	// files: [a b]
+	//   -c, --color INT INT INT  (repeatable)
+	//   --files STRING [STRING...]
+	//   -m, --move FLOAT FLOAT
+}
+
+func ExampleNewParser_capture() {
+	op := NewParser([]string{
+		// Like find -exec, take every argument up to a ";"
+		"exec...;",
+		// Take every remaining argument, including options and --
+		"c|command...",
+		"v|verbose",
+	})
+
+	args := []string{"--exec", "rm", "-f", "{}", ";", "-v", "-c", "ssh", "-v", "--", "host"}
+	if err := op.ProcessAll(args); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("exec: %q\n", op.Results["exec"])
+	fmt.Printf("command: %q\n", op.Results["command"])
+	fmt.Printf("verbose: %v\n", op.Results["verbose"])
+	op.WriteHelp(os.Stdout)
+
+	// Output:
+	// exec: ["rm" "-f" "{}"]
+	// command: ["ssh" "-v" "--" "host"]
+	// verbose: true
+	//   -c, --command ARGS...
+	//   --exec ARGS... ;
+	//   -v, --verbose
+}
+
+func ExampleNewParser_counterLimits() {
+	op := NewParser([]string{
+		// -v raises the verbosity and -q lowers it, staying between
+		// -1 and 3.  --verbose=N sets it directly.
+		"v|verbose|-q+{-1,3}",
+	})
+
+	for _, args := range [][]string{
+		{"-v", "-v", "-q"},
+		{"-q", "-q", "-q"},
+		{"--verbose=2", "-v", "-v"},
+	} {
+		op.Reset()
+		if err := op.ProcessAll(args); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%v: %v\n", args, op.Results["verbose"])
+	}
+	op.WriteHelp(os.Stdout)
+
+	// Output:
+	// [-v -v -q]: 1
+	// [-q -q -q]: -1
+	// [--verbose=2 -v -v]: 3
+	//   -v, --verbose  (decrease with -q)
+}
+
+func ExampleOptionParser_Repeat() {
+	op := NewParser([]string{"u|user=s", "v|verbose", "e|env=s"})
+	// keep the first --env, and never allow a second --user
+	op.Repeat = RepeatFirst
+	if err := op.Describe("user", OptionMeta{Repeat: RepeatError}); err != nil {
+		panic(err)
+	}
+
+	if err := op.ProcessAll([]string{"-e", "prod", "-v", "--env=dev"}); err != nil {
+		panic(err)
+	}
+	fmt.Printf("env: %v\n", op.Results["env"])
+
+	if err := op.ProcessAll([]string{"--user", "alice", "-v", "-u", "root"}); err != nil {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// env: prod
+	// option --user given more than once, as --user at argument 1 and -u at argument 4
+}
+
+func ExampleNewParser_negate() {
+	op := NewParser([]string{
+		// --no-include clears the list, including any defaults
+		"I|include=s@!",
+		// --no-color turns the flag off
+		"color!",
+	})
+	op.Results = map[string]interface{}{
+		"include": []string{"/usr/include", "/usr/local/include"},
+		"color":   true,
+	}
+
+	if err := op.ProcessAll([]string{"--no-include", "-I", "./include", "--no-color"}); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("include: %v\n", op.Results["include"])
+	fmt.Printf("color: %v\n", op.Results["color"])
+	op.WriteHelp(os.Stdout)
+
+	// Output:
+	// include: [./include]
+	// color: false
+	//   --color               (disable with --no-color)
+	//   -I, --include STRING  (repeatable) (clear with --no-include)
+}
@@ -0,0 +1,177 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"os"
+	"reflect"
+)
+
+// filePath is the parsed value of =r and =w options.  Files are not
+// opened while the arguments are parsed, so that a bad argument later
+// on does not leave an =w file truncated.  See openFiles.
+type filePath string
+
+// pendingFile is an occurrence of a file option waiting for openFiles.
+type pendingFile struct {
+	opt   option
+	index int
+}
+
+func (o *option) isFile() bool {
+	return o.dataType == dtREADFILE || o.dataType == dtWRITEFILE
+}
+
+// open opens path for reading or writing depending on the option type.
+// A path of "-" is stdin for =r options and stdout for =w options.
+func (o *OptionParser) open(opt option, path filePath) (*os.File, error) {
+	var f *os.File
+	var err error
+	switch {
+	case path == "-" && opt.dataType == dtREADFILE:
+		return os.Stdin, nil
+	case path == "-":
+		return os.Stdout, nil
+	case opt.dataType == dtREADFILE:
+		f, err = os.Open(string(path))
+	default:
+		f, err = os.Create(string(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.files = append(o.files, f)
+	return f, nil
+}
+
+// openValue replaces the file paths in the parsed value with opened
+// files.
+func (o *OptionParser) openValue(opt option, value interface{}) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case filePath:
+		return o.open(opt, v)
+	case keyVal:
+		v.val, err = o.openValue(opt, v.val)
+		return v, err
+	case []interface{}:
+		opened := make([]interface{}, len(v))
+		for i, val := range v {
+			if opened[i], err = o.openValue(opt, val); err != nil {
+				return nil, err
+			}
+		}
+		return opened, nil
+	case []filePath:
+		files := make([]*os.File, len(v))
+		for i, path := range v {
+			if files[i], err = o.open(opt, path); err != nil {
+				return nil, err
+			}
+		}
+		return files, nil
+	}
+	return value, nil
+}
+
+// openFiles opens the files given to =r and =w options once all of the
+// arguments have been parsed, then stores them in the order they were
+// given.  Every =r file is opened before any =w file is created, so a
+// missing input does not leave an output truncated, and on error the
+// files opened so far are closed without being stored.  Only the last
+// occurrence of an option holding a single file is opened, and the
+// file it replaces is closed.
+func (o *OptionParser) openFiles() error {
+	pending := o.pending
+	o.pending = nil
+	last := make(map[string]int)
+	for i, p := range pending {
+		if p.opt.action == atASSIGN {
+			last[p.opt.name] = i
+		}
+	}
+	opened := len(o.files)
+	values := make([]interface{}, len(pending))
+	for _, kind := range []dataType{dtREADFILE, dtWRITEFILE} {
+		for i, p := range pending {
+			if j, ok := last[p.opt.name]; p.opt.dataType != kind || ok && j != i {
+				continue
+			}
+			value, err := o.openValue(p.opt, o.Occurrences[p.index].Value)
+			if err != nil {
+				for _, f := range o.files[opened:] {
+					f.Close()
+				}
+				o.files = o.files[:opened]
+				return err
+			}
+			values[i] = value
+		}
+	}
+	for i, p := range pending {
+		occ := &o.Occurrences[p.index]
+		if j, ok := last[p.opt.name]; ok && j != i {
+			occ.Value = string(occ.Value.(filePath))
+			continue
+		}
+		occ.Value = values[i]
+		if p.opt.action == atASSIGN {
+			o.closeFile(o.current(p.opt))
+		}
+		if err := o.applyOccurrence(p.opt, *occ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// current returns the value held for opt, or nil for callbacks and
+// options not yet set.
+func (o *OptionParser) current(opt option) interface{} {
+	if opt.dest.Kind() == reflect.Ptr {
+		return opt.dest.Elem().Interface()
+	}
+	if !opt.dest.IsValid() {
+		return o.Results[opt.name]
+	}
+	return nil
+}
+
+// closeFile closes v if it is a file opened by the parser.
+func (o *OptionParser) closeFile(v interface{}) {
+	f, ok := v.(*os.File)
+	if !ok {
+		return
+	}
+	for i, opened := range o.files {
+		if opened == f {
+			f.Close()
+			o.files = append(o.files[:i], o.files[i+1:]...)
+			return
+		}
+	}
+}
+
+// closeFiles closes every file opened by the parser.
+func (o *OptionParser) closeFiles() {
+	for _, f := range o.files {
+		f.Close()
+	}
+	o.files = nil
+}
@@ -0,0 +1,3 @@
+module github.com/coryb/optigo
+
+go 1.21
@@ -0,0 +1,122 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteHelp writes a table of the registered options with their
+// usage text, as set with Describe, to w.
+func (o *OptionParser) WriteHelp(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, info := range o.Options() {
+		line := "  " + info.Syntax()
+		if details := info.Details(); details != "" {
+			line += "\t" + details
+		}
+		if _, err := fmt.Fprintln(tw, line); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// Placeholder returns the name used for the option value in help
+// output, such as "INT" or "KEY=STRING".  It is empty for options
+// that do not take a value.
+func (info OptionInfo) Placeholder() string {
+	if !info.TakesValue {
+		return ""
+	}
+	placeholder := strings.ToUpper(info.Type)
+	if info.Action == "capture" {
+		return strings.TrimSpace("ARGS... " + info.Terminator)
+	}
+	if info.Action == "nested" {
+		placeholder = "PATH=" + placeholder
+	} else if info.KeyType == "string" {
+		placeholder = "KEY=" + placeholder
+	} else if info.KeyType != "" {
+		placeholder = strings.ToUpper(info.KeyType) + "=" + placeholder
+	}
+	if info.ListSeparator != "" {
+		placeholder += "[" + info.ListSeparator + "...]"
+	}
+	if info.MinValues > 0 {
+		placeholders := make([]string, info.MinValues)
+		for i := range placeholders {
+			placeholders[i] = placeholder
+		}
+		if info.MaxValues == -1 {
+			placeholders = append(placeholders, "["+placeholder+"...]")
+		}
+		for i := info.MinValues; i < info.MaxValues; i++ {
+			placeholders = append(placeholders, "["+placeholder+"]")
+		}
+		placeholder = strings.Join(placeholders, " ")
+	}
+	return placeholder
+}
+
+// Syntax returns the aliases of the option joined with their value
+// placeholder, such as "-n, --name STRING".
+func (info OptionInfo) Syntax() string {
+	syntax := strings.Join(info.Aliases, ", ")
+	if placeholder := info.Placeholder(); placeholder != "" {
+		syntax += " " + placeholder
+	}
+	return syntax
+}
+
+// Details returns the usage text of the option followed by notes on
+// whether it can be repeated, its default and whether it is required.
+func (info OptionInfo) Details() string {
+	return info.details(true)
+}
+
+func (info OptionInfo) details(withDefault bool) string {
+	details := make([]string, 0)
+	if info.Usage != "" {
+		details = append(details, info.Usage)
+	}
+	if info.Action == "append" || info.Action == "map" || info.Action == "maplist" {
+		details = append(details, "(repeatable)")
+	}
+	if len(info.DecrementAliases) > 0 {
+		details = append(details, fmt.Sprintf("(decrease with %s)", strings.Join(info.DecrementAliases, ", ")))
+	}
+	if len(info.NegatedAliases) > 0 {
+		verb := "clear"
+		if !info.TakesValue {
+			verb = "disable"
+		}
+		details = append(details, fmt.Sprintf("(%s with %s)", verb, strings.Join(info.NegatedAliases, ", ")))
+	}
+	if withDefault && info.Default != nil {
+		details = append(details, fmt.Sprintf("(default: %v)", info.Default))
+	}
+	if info.Required {
+		details = append(details, "(required)")
+	}
+	return strings.Join(details, " ")
+}
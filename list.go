@@ -0,0 +1,98 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// listSeparators are the characters allowed in an append spec like
+// "tags=s@," or "tags=s[,]" to split each value into several values.
+const listSeparators = ",;:"
+
+// parseList splits val on the option list separator and parses each
+// of the parts.
+func (o *option) parseList(val string) (interface{}, error) {
+	parts, err := splitList(val, o.listSep)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for option --%s: %s", o.name, err)
+	}
+	values := make([]interface{}, len(parts))
+	for i, part := range parts {
+		if values[i], err = o.parseValue(part); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// splitList splits val on sep.  A value starting with a single or
+// double quote can include sep up to the closing quote, and outside of
+// quotes a backslash escapes the next character.  Quotes elsewhere in a
+// value, like the apostrophe in O'Brien, are kept as they are.
+func splitList(val string, sep byte) ([]string, error) {
+	parts := make([]string, 0)
+	var buf strings.Builder
+	var quote byte
+	start := true
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				buf.WriteByte(c)
+			}
+		case c == '\\':
+			if i+1 >= len(val) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			buf.WriteByte(val[i])
+		case start && (c == '"' || c == '\''):
+			quote = c
+		case c == sep:
+			parts = append(parts, buf.String())
+			buf.Reset()
+			start = true
+			continue
+		default:
+			buf.WriteByte(c)
+		}
+		start = false
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	return append(parts, buf.String()), nil
+}
+
+// escapeListValue escapes val so that splitList returns it unchanged.
+func escapeListValue(val string, sep byte) string {
+	var escaped strings.Builder
+	for i := 0; i < len(val); i++ {
+		if c := val[i]; c == sep || c == '\\' || i == 0 && (c == '"' || c == '\'') {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteByte(val[i])
+	}
+	return escaped.String()
+}
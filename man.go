@@ -0,0 +1,211 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Doc describes a program for generating reference documentation from
+// the options of an OptionParser.
+type Doc struct {
+	// Name is the program name.
+	Name string
+
+	// Section is the manual section, "1" if empty.
+	Section string
+
+	// Date is shown in the man page footer.
+	Date string
+
+	// Summary is a one line description of the program.
+	Summary string
+
+	// Synopsis is the usage line, generated from Name if empty.
+	Synopsis string
+
+	// Description is the long description of the program.  Paragraphs
+	// are separated by blank lines.
+	Description string
+
+	// Files maps paths used by the program to their description.
+	Files map[string]string
+
+	// Commands are the subcommands of the program.
+	Commands map[string]*Command
+}
+
+func (d *Doc) synopsis() string {
+	if d.Synopsis != "" {
+		return d.Synopsis
+	}
+	synopsis := d.Name + " [OPTIONS]"
+	if len(d.Commands) > 0 {
+		synopsis += " COMMAND [ARGS]"
+	}
+	return synopsis
+}
+
+func (d *Doc) commandNames() []string {
+	names := make([]string, 0, len(d.Commands))
+	for name := range d.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Doc) fileNames() []string {
+	names := make([]string, 0, len(d.Files))
+	for name := range d.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func paragraphs(text string) []string {
+	paras := make([]string, 0)
+	for _, p := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paras = append(paras, p)
+		}
+	}
+	return paras
+}
+
+// envOptions returns the options of the parser and of every command
+// that are read from environment variables.
+func (d *Doc) envOptions(o *OptionParser) []OptionInfo {
+	parsers := []*OptionParser{o}
+	for _, name := range d.commandNames() {
+		if d.Commands[name].Parser != nil {
+			parsers = append(parsers, d.Commands[name].Parser)
+		}
+	}
+	infos := make([]OptionInfo, 0)
+	for _, p := range parsers {
+		for _, info := range p.Options() {
+			if info.Env != "" {
+				infos = append(infos, info)
+			}
+		}
+	}
+	return infos
+}
+
+// WriteMan writes a man(7) formatted page for the program described
+// by doc to w, with the options of the parser in the OPTIONS section.
+func (o *OptionParser) WriteMan(w io.Writer, doc Doc) error {
+	bw := bufio.NewWriter(w)
+	section := doc.Section
+	if section == "" {
+		section = "1"
+	}
+
+	fmt.Fprintf(bw, ".TH %s %s %s\n", roffQuote(strings.ToUpper(doc.Name)), section, roffQuote(doc.Date))
+	fmt.Fprintf(bw, ".SH NAME\n%s", roffEscape(doc.Name))
+	if doc.Summary != "" {
+		fmt.Fprintf(bw, " \\- %s", roffEscape(doc.Summary))
+	}
+	fmt.Fprintf(bw, "\n.SH SYNOPSIS\n%s\n", roffEscape(doc.synopsis()))
+
+	if paras := paragraphs(doc.Description); len(paras) > 0 {
+		fmt.Fprintln(bw, ".SH DESCRIPTION")
+		for i, p := range paras {
+			if i > 0 {
+				fmt.Fprintln(bw, ".PP")
+			}
+			fmt.Fprintln(bw, roffEscape(p))
+		}
+	}
+
+	if options := o.Options(); len(options) > 0 {
+		fmt.Fprintln(bw, ".SH OPTIONS")
+		writeManOptions(bw, options)
+	}
+
+	if len(doc.Commands) > 0 {
+		fmt.Fprintln(bw, ".SH SUBCOMMANDS")
+		for _, name := range doc.commandNames() {
+			cmd := doc.Commands[name]
+			fmt.Fprintf(bw, ".SS %s\n", roffEscape(name))
+			if cmd.Summary != "" {
+				fmt.Fprintln(bw, roffEscape(cmd.Summary))
+			}
+			if cmd.Parser != nil {
+				writeManOptions(bw, cmd.Parser.Options())
+			}
+		}
+	}
+
+	if env := doc.envOptions(o); len(env) > 0 {
+		fmt.Fprintln(bw, ".SH ENVIRONMENT")
+		for _, info := range env {
+			fmt.Fprintf(bw, ".TP\n.B %s\nUsed for %s when it is not given.\n", roffEscape(info.Env), roffEscape(info.Aliases[len(info.Aliases)-1]))
+		}
+	}
+
+	if len(doc.Files) > 0 {
+		fmt.Fprintln(bw, ".SH FILES")
+		for _, name := range doc.fileNames() {
+			fmt.Fprintf(bw, ".TP\n.I %s\n%s\n", roffEscape(name), roffEscape(doc.Files[name]))
+		}
+	}
+	return bw.Flush()
+}
+
+func writeManOptions(w io.Writer, options []OptionInfo) {
+	for _, info := range options {
+		aliases := make([]string, len(info.Aliases))
+		for i, alias := range info.Aliases {
+			aliases[i] = "\\fB" + roffEscape(alias) + "\\fR"
+		}
+		fmt.Fprintf(w, ".TP\n%s", strings.Join(aliases, ", "))
+		if placeholder := info.Placeholder(); placeholder != "" {
+			fmt.Fprintf(w, " \\fI%s\\fR", roffEscape(placeholder))
+		}
+		fmt.Fprintln(w)
+		if details := info.Details(); details != "" {
+			fmt.Fprintln(w, roffEscape(details))
+		}
+	}
+}
+
+// roffEscape escapes text for use in a roff document, protecting
+// backslashes, hyphens and control characters at the start of lines.
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\e")
+	text = strings.ReplaceAll(text, "-", "\\-")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = "\\&" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func roffQuote(text string) string {
+	return `"` + strings.ReplaceAll(roffEscape(text), `"`, `\(dq`) + `"`
+}
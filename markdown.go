@@ -0,0 +1,184 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// anchor returns the anchor id used for an option in generated docs,
+// prefixed by the command name for subcommand options.
+func anchor(command string, info OptionInfo) string {
+	id := "option-" + info.Name
+	if command != "" {
+		id = command + "-" + id
+	}
+	return id
+}
+
+func docDefault(info OptionInfo) string {
+	if info.Default == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", info.Default)
+}
+
+// WriteMarkdown writes Markdown reference documentation for the
+// program described by doc to w.  Every option has an anchor named
+// `option-NAME`, or `COMMAND-option-NAME` for subcommand options.
+func (o *OptionParser) WriteMarkdown(w io.Writer, doc Doc) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n\n", doc.Name)
+	if doc.Summary != "" {
+		fmt.Fprintf(bw, "%s\n\n", doc.Summary)
+	}
+	fmt.Fprintf(bw, "```\n%s\n```\n\n", doc.synopsis())
+	for _, p := range paragraphs(doc.Description) {
+		fmt.Fprintf(bw, "%s\n\n", p)
+	}
+
+	if options := o.Options(); len(options) > 0 {
+		fmt.Fprintf(bw, "## Options\n\n")
+		writeMarkdownOptions(bw, "", options)
+	}
+
+	if len(doc.Commands) > 0 {
+		fmt.Fprintf(bw, "## Commands\n\n")
+		for _, name := range doc.commandNames() {
+			cmd := doc.Commands[name]
+			fmt.Fprintf(bw, "### %s\n\n", name)
+			if cmd.Summary != "" {
+				fmt.Fprintf(bw, "%s\n\n", cmd.Summary)
+			}
+			if cmd.Parser != nil {
+				writeMarkdownOptions(bw, name, cmd.Parser.Options())
+			}
+		}
+	}
+
+	if env := doc.envOptions(o); len(env) > 0 {
+		fmt.Fprintf(bw, "## Environment\n\n| Variable | Option |\n| --- | --- |\n")
+		for _, info := range env {
+			fmt.Fprintf(bw, "| `%s` | `%s` |\n", info.Env, info.Aliases[len(info.Aliases)-1])
+		}
+		fmt.Fprintln(bw)
+	}
+
+	if len(doc.Files) > 0 {
+		fmt.Fprintf(bw, "## Files\n\n")
+		for _, name := range doc.fileNames() {
+			fmt.Fprintf(bw, "* `%s`: %s\n", name, doc.Files[name])
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownOptions(w io.Writer, command string, options []OptionInfo) {
+	fmt.Fprintf(w, "| Option | Type | Default | Environment | Description |\n| --- | --- | --- | --- | --- |\n")
+	for _, info := range options {
+		cells := []string{
+			fmt.Sprintf(`<a id="%s"></a>%s`, anchor(command, info), markdownCode(info.Syntax())),
+			info.Type,
+			markdownCode(docDefault(info)),
+			markdownCode(info.Env),
+			markdownEscape(info.details(false)),
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	fmt.Fprintln(w)
+}
+
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(text, "|", `\|`) + "`"
+}
+
+func markdownEscape(text string) string {
+	replacer := strings.NewReplacer("|", `\|`, "\n", " ", "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;")
+	return replacer.Replace(text)
+}
+
+// WriteHTML writes an HTML fragment of reference documentation for
+// the program described by doc to w, using the same structure and
+// anchors as WriteMarkdown.
+func (o *OptionParser) WriteHTML(w io.Writer, doc Doc) error {
+	bw := bufio.NewWriter(w)
+	esc := html.EscapeString
+	fmt.Fprintf(bw, "<h1>%s</h1>\n", esc(doc.Name))
+	if doc.Summary != "" {
+		fmt.Fprintf(bw, "<p>%s</p>\n", esc(doc.Summary))
+	}
+	fmt.Fprintf(bw, "<pre>%s</pre>\n", esc(doc.synopsis()))
+	for _, p := range paragraphs(doc.Description) {
+		fmt.Fprintf(bw, "<p>%s</p>\n", esc(p))
+	}
+
+	if options := o.Options(); len(options) > 0 {
+		fmt.Fprintf(bw, "<h2>Options</h2>\n")
+		writeHTMLOptions(bw, "", options)
+	}
+
+	if len(doc.Commands) > 0 {
+		fmt.Fprintf(bw, "<h2>Commands</h2>\n")
+		for _, name := range doc.commandNames() {
+			cmd := doc.Commands[name]
+			fmt.Fprintf(bw, "<h3 id=\"%s\">%s</h3>\n", esc(name), esc(name))
+			if cmd.Summary != "" {
+				fmt.Fprintf(bw, "<p>%s</p>\n", esc(cmd.Summary))
+			}
+			if cmd.Parser != nil {
+				writeHTMLOptions(bw, name, cmd.Parser.Options())
+			}
+		}
+	}
+
+	if env := doc.envOptions(o); len(env) > 0 {
+		fmt.Fprintf(bw, "<h2>Environment</h2>\n<table>\n<tr><th>Variable</th><th>Option</th></tr>\n")
+		for _, info := range env {
+			fmt.Fprintf(bw, "<tr><td><code>%s</code></td><td><code>%s</code></td></tr>\n", esc(info.Env), esc(info.Aliases[len(info.Aliases)-1]))
+		}
+		fmt.Fprintf(bw, "</table>\n")
+	}
+
+	if len(doc.Files) > 0 {
+		fmt.Fprintf(bw, "<h2>Files</h2>\n<dl>\n")
+		for _, name := range doc.fileNames() {
+			fmt.Fprintf(bw, "<dt><code>%s</code></dt><dd>%s</dd>\n", esc(name), esc(doc.Files[name]))
+		}
+		fmt.Fprintf(bw, "</dl>\n")
+	}
+	return bw.Flush()
+}
+
+func writeHTMLOptions(w io.Writer, command string, options []OptionInfo) {
+	esc := html.EscapeString
+	fmt.Fprintf(w, "<table>\n<tr><th>Option</th><th>Type</th><th>Default</th><th>Environment</th><th>Description</th></tr>\n")
+	for _, info := range options {
+		fmt.Fprintf(w, "<tr id=\"%s\"><td><code>%s</code></td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			esc(anchor(command, info)), esc(info.Syntax()), esc(info.Type), esc(docDefault(info)), esc(info.Env), esc(info.details(false)))
+	}
+	fmt.Fprintf(w, "</table>\n")
+}
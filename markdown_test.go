@@ -0,0 +1,112 @@
+package optigo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func docTestParser() (OptionParser, Doc) {
+	op := NewParser([]string{"v|verbose+", "c|config=s", "l|label=s%"})
+	op.Describe("config", OptionMeta{Usage: "Read settings from a | separated list.", Env: "MYTOOL_CONFIG", Default: "~/.mytool"})
+
+	add := NewParser([]string{"f|force"})
+	add.Describe("force", OptionMeta{Usage: "Overwrite <existing> items."})
+
+	return op, Doc{
+		Name:        "mytool",
+		Summary:     "manage things",
+		Description: "Manage all of the things.",
+		Files:       map[string]string{"~/.mytool": "Default configuration."},
+		Commands: map[string]*Command{
+			"add": {Summary: "Add a thing.", Parser: &add},
+		},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	op, doc := docTestParser()
+	var buf bytes.Buffer
+	if err := op.WriteMarkdown(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# mytool\n" +
+		"\n" +
+		"manage things\n" +
+		"\n" +
+		"```\n" +
+		"mytool [OPTIONS] COMMAND [ARGS]\n" +
+		"```\n" +
+		"\n" +
+		"Manage all of the things.\n" +
+		"\n" +
+		"## Options\n" +
+		"\n" +
+		"| Option | Type | Default | Environment | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| <a id=\"option-config\"></a>`-c, --config STRING` | string | `~/.mytool` | `MYTOOL_CONFIG` | Read settings from a \\| separated list. |\n" +
+		"| <a id=\"option-label\"></a>`-l, --label KEY=STRING` | string |  |  | (repeatable) |\n" +
+		"| <a id=\"option-verbose\"></a>`-v, --verbose` | int |  |  |  |\n" +
+		"\n" +
+		"## Commands\n" +
+		"\n" +
+		"### add\n" +
+		"\n" +
+		"Add a thing.\n" +
+		"\n" +
+		"| Option | Type | Default | Environment | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| <a id=\"add-option-force\"></a>`-f, --force` | bool |  |  | Overwrite &lt;existing> items. |\n" +
+		"\n" +
+		"## Environment\n" +
+		"\n" +
+		"| Variable | Option |\n" +
+		"| --- | --- |\n" +
+		"| `MYTOOL_CONFIG` | `--config` |\n" +
+		"\n" +
+		"## Files\n" +
+		"\n" +
+		"* `~/.mytool`: Default configuration.\n" +
+		"\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected markdown:\n%s", buf.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	op, doc := docTestParser()
+	var buf bytes.Buffer
+	if err := op.WriteHTML(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<h1>mytool</h1>
+<p>manage things</p>
+<pre>mytool [OPTIONS] COMMAND [ARGS]</pre>
+<p>Manage all of the things.</p>
+<h2>Options</h2>
+<table>
+<tr><th>Option</th><th>Type</th><th>Default</th><th>Environment</th><th>Description</th></tr>
+<tr id="option-config"><td><code>-c, --config STRING</code></td><td>string</td><td>~/.mytool</td><td>MYTOOL_CONFIG</td><td>Read settings from a | separated list.</td></tr>
+<tr id="option-label"><td><code>-l, --label KEY=STRING</code></td><td>string</td><td></td><td></td><td>(repeatable)</td></tr>
+<tr id="option-verbose"><td><code>-v, --verbose</code></td><td>int</td><td></td><td></td><td></td></tr>
+</table>
+<h2>Commands</h2>
+<h3 id="add">add</h3>
+<p>Add a thing.</p>
+<table>
+<tr><th>Option</th><th>Type</th><th>Default</th><th>Environment</th><th>Description</th></tr>
+<tr id="add-option-force"><td><code>-f, --force</code></td><td>bool</td><td></td><td></td><td>Overwrite &lt;existing&gt; items.</td></tr>
+</table>
+<h2>Environment</h2>
+<table>
+<tr><th>Variable</th><th>Option</th></tr>
+<tr><td><code>MYTOOL_CONFIG</code></td><td><code>--config</code></td></tr>
+</table>
+<h2>Files</h2>
+<dl>
+<dt><code>~/.mytool</code></dt><dd>Default configuration.</dd>
+</dl>
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected html:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"reflect"
+	"strings"
+)
+
+// negatedAliases returns the --no-NAME aliases for the long aliases
+// of a negatable spec like "include=s@!".
+func negatedAliases(aliases []string) []string {
+	neg := make([]string, 0)
+	for _, alias := range aliases {
+		if strings.HasPrefix(alias, "--") {
+			neg = append(neg, "--no-"+alias[2:])
+		}
+	}
+	return neg
+}
+
+// canNegate reports whether options with the action can be negated:
+// flags are set to false and lists and maps are cleared.
+func (o *option) canNegate() bool {
+	if o.action == atASSIGN {
+		return o.unary
+	}
+	return o.action == atAPPEND || o.isMap()
+}
+
+// negates reports whether alias is one of the --no-NAME aliases.
+func (o *option) negates(alias string) bool {
+	for _, neg := range o.neg {
+		if neg == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// clear empties the list or map held by opt so that following values
+// start from scratch, dropping any defaults.
+func (o *OptionParser) clear(opt option) {
+	if opt.dest.IsValid() {
+		val := opt.dest.Elem()
+		if val.Kind() == reflect.Map {
+			val.Set(reflect.MakeMap(val.Type()))
+		} else {
+			val.Set(reflect.MakeSlice(val.Type(), 0, 0))
+		}
+		return
+	}
+	delete(o.Results, opt.name)
+	o.initResultKey(&opt)
+}
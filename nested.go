@@ -0,0 +1,94 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathSeparators are the characters allowed in a nested map spec like
+// "set=*{.}" to split keys into paths.
+const pathSeparators = "./:"
+
+// inferValue converts val to an int64, float64 or bool if it can be
+// parsed as one, otherwise val is returned as a string.
+func inferValue(val string) interface{} {
+	if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(val, 64); err == nil {
+		return f
+	}
+	if val == "true" || val == "false" {
+		return val == "true"
+	}
+	return val
+}
+
+// setNested splits the key on the option path separator and stores
+// the value in m, creating intermediate maps as needed.  It is an error
+// for a path to be used for both a value and nested keys.
+func (o *option) setNested(m map[string]interface{}, kv keyVal) error {
+	key := kv.key.(string)
+	path := strings.Split(key, string(o.pathSep))
+	for i, part := range path[:len(path)-1] {
+		switch child := m[part].(type) {
+		case nil:
+			next := make(map[string]interface{})
+			m[part] = next
+			m = next
+		case map[string]interface{}:
+			m = child
+		default:
+			return fmt.Errorf("option --%s: cannot set %s, %s already holds the value %v", o.name, key, strings.Join(path[:i+1], string(o.pathSep)), child)
+		}
+	}
+	last := path[len(path)-1]
+	if _, ok := m[last].(map[string]interface{}); ok {
+		return fmt.Errorf("option --%s: cannot set %s, it already holds nested keys", o.name, key)
+	}
+	m[last] = kv.val
+	return nil
+}
+
+// flattenNested returns the leaf paths of the nested map m joined with
+// sep, sorted, along with their values.
+func flattenNested(m map[string]interface{}, sep string) ([]string, map[string]interface{}) {
+	leaves := make(map[string]interface{})
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if child, ok := v.(map[string]interface{}); ok {
+				walk(prefix+k+sep, child)
+			} else {
+				leaves[prefix+k] = v
+			}
+		}
+	}
+	walk("", m)
+	paths := make([]string, 0, len(leaves))
+	for path := range leaves {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, leaves
+}
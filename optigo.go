@@ -19,7 +19,10 @@
 package optigo
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"os"
 	"reflect"
 	"strconv"
 	"strings"
@@ -32,8 +35,41 @@ const (
 	atAPPEND
 	atASSIGN
 	atMAP
+	atMAPLIST
+	atNESTED
+	atCAPTURE
 )
 
+func (a actionType) String() string {
+	switch a {
+	case atINCREMENT:
+		return "increment"
+	case atAPPEND:
+		return "append"
+	case atASSIGN:
+		return "assign"
+	case atMAP:
+		return "map"
+	case atMAPLIST:
+		return "maplist"
+	case atNESTED:
+		return "nested"
+	case atCAPTURE:
+		return "capture"
+	}
+	return fmt.Sprintf("actionType(%d)", int(a))
+}
+
+// ErrStop can be returned from an option callback or from
+// OptionParser.Positional to stop processing early, for example after
+// printing usage for `--help`.  Errors wrapping ErrStop, as made by
+// fmt.Errorf with %w, stop processing the same way.  The Process
+// routines return the error as given, so check for it with errors.Is,
+// and any unprocessed arguments are left in OptionParser.Args.
+var ErrStop = errors.New("option processing stopped")
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 type dataType int
 
 const (
@@ -41,37 +77,96 @@ const (
 	dtINTEGER
 	dtFLOAT
 	dtBOOLEAN
+	dtREADFILE
+	dtWRITEFILE
+	dtAUTO
 )
 
+func (t dataType) String() string {
+	switch t {
+	case dtSTRING:
+		return "string"
+	case dtINTEGER:
+		return "int"
+	case dtFLOAT:
+		return "float"
+	case dtBOOLEAN:
+		return "bool"
+	case dtREADFILE:
+		return "infile"
+	case dtWRITEFILE:
+		return "outfile"
+	case dtAUTO:
+		return "any"
+	}
+	return fmt.Sprintf("dataType(%d)", int(t))
+}
+
 type option struct {
 	name     string
 	unary    bool
 	dest     reflect.Value
 	action   actionType
 	dataType dataType
+	keyType  dataType
+	pathSep  byte
+	listSep  byte
+	minVals  int
+	maxVals  int
+	term     string
+	minCount int64
+	maxCount int64
+	aliases  []string
+	decr     []string
+	neg      []string
+	dflt     reflect.Value
+	meta     *OptionMeta
 }
 
 type keyVal struct {
-	key string
+	key interface{}
 	val interface{}
 }
 
-type dashDash struct{}
+var keyValType = reflect.TypeOf(keyVal{})
+
+// valueType returns the type of the values passed to callbacks for
+// the option.
+func (o *option) valueType() reflect.Type {
+	elem := o.dataType.goType()
+	switch {
+	case o.isMap():
+		return keyValType
+	case o.action == atINCREMENT:
+		return reflect.TypeOf(false)
+	case o.action == atCAPTURE:
+		return reflect.TypeOf([]string(nil))
+	case o.takesValues():
+		return reflect.SliceOf(elem)
+	}
+	return elem
+}
+
+// dashDash is returned from processSome when option processing was
+// stopped early, either by `--` or by RequireOrder.  ProcessAll only
+// reports unknown options among the first `checked` elements of
+// OptionParser.Args: those before the stop for RequireOrder, and none
+// at all once `--` is seen.
+type dashDash struct {
+	checked int
+}
 
 func (e *dashDash) Error() string {
 	return "found -- in arguments"
 }
 
 func (o *option) parseValue(val string) (interface{}, error) {
-	var keyval keyVal
-	if o.action == atMAP {
-		parts := strings.SplitN(val, "=", 2)
-		val = parts[1]
-		keyval = keyVal{key: parts[0]}
-	}
+	return parseData(o.dataType, val)
+}
 
+func parseData(t dataType, val string) (interface{}, error) {
 	var parsed interface{}
-	switch o.dataType {
+	switch t {
 	case dtSTRING:
 		parsed = val
 	case dtINTEGER:
@@ -86,45 +181,210 @@ func (o *option) parseValue(val string) (interface{}, error) {
 		} else {
 			return nil, err
 		}
+	case dtREADFILE, dtWRITEFILE:
+		parsed = filePath(val)
+	case dtAUTO:
+		parsed = inferValue(val)
 	default:
 		return nil, fmt.Errorf("Unable to parse value: %s", val)
 	}
+	return parsed, nil
+}
+
+// zeroValue returns the value used for map options given a key
+// without a value when OptionParser.MapKeyOnly is set.
+func (o *option) zeroValue() (interface{}, error) {
+	switch o.dataType {
+	case dtSTRING:
+		return "", nil
+	case dtINTEGER:
+		return int64(0), nil
+	case dtFLOAT:
+		return float64(0), nil
+	case dtAUTO:
+		return true, nil
+	}
+	return nil, fmt.Errorf("missing value for key")
+}
 
-	if o.action == atMAP {
-		keyval.val = parsed
-		return keyval, nil
+// parseValue parses val for opt.  Values for map options are split
+// into a key and value using the parser's MapSeparators.
+func (o *OptionParser) parseValue(opt option, val string) (interface{}, error) {
+	if opt.listSep != 0 {
+		return opt.parseList(val)
+	}
+	if !opt.isMap() {
+		return opt.parseValue(val)
+	}
+	rawKey, raw, ok := splitMapValue(val, o.MapSeparators)
+	key, err := parseData(opt.keyType, rawKey)
+	if err != nil {
+		return nil, err
+	}
+	var parsed interface{}
+	if ok {
+		parsed, err = opt.parseValue(raw)
+	} else if o.MapKeyOnly {
+		parsed, err = opt.zeroValue()
 	} else {
-		return parsed, nil
+		return nil, fmt.Errorf("invalid value for option --%s, expected key%cvalue: %s", opt.name, o.mapSeparators()[0], val)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return keyVal{key: key, val: parsed}, nil
+}
+
+func (o *OptionParser) mapSeparators() string {
+	if o.MapSeparators == "" {
+		return "="
+	}
+	return o.MapSeparators
+}
+
+// splitMapValue splits val into a key and value at the first unescaped
+// separator.  Within the key a backslash escapes a separator or another
+// backslash.  With no separators val is split at the first "=" and
+// backslashes are kept as they are.  It returns false if there is no
+// separator.
+func splitMapValue(val, separators string) (string, string, bool) {
+	if separators == "" {
+		return strings.Cut(val, "=")
+	}
+	var key strings.Builder
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		if c == '\\' && i+1 < len(val) && (val[i+1] == '\\' || strings.IndexByte(separators, val[i+1]) != -1) {
+			i++
+			key.WriteByte(val[i])
+		} else if strings.IndexByte(separators, c) != -1 {
+			return key.String(), val[i+1:], true
+		} else {
+			key.WriteByte(c)
+		}
+	}
+	return key.String(), "", false
+}
+
+// escapeMapKey escapes key so that splitMapValue will return it
+// unchanged when it is joined to a value by one of the separators.
+func escapeMapKey(key, separators string) string {
+	if separators == "" {
+		return key
+	}
+	var escaped strings.Builder
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' || strings.IndexByte(separators, key[i]) != -1 {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteByte(key[i])
+	}
+	return escaped.String()
+}
+
+func (o *option) isMap() bool {
+	return o.action == atMAP || o.action == atMAPLIST || o.action == atNESTED
+}
+
+// typeLetters maps the letters used in specs to data types.
+var typeLetters = map[byte]dataType{
+	's': dtSTRING,
+	'i': dtINTEGER,
+	'f': dtFLOAT,
+	'r': dtREADFILE,
+	'w': dtWRITEFILE,
+}
+
+// mapSuffix checks if spec ends with one of the map suffixes: "%",
+// "{}", or "{s}", "{i}" or "{f}" for maps with typed keys.  It
+// returns the spec without the suffix and the key type.
+func mapSuffix(spec string) (string, dataType, bool) {
+	if strings.HasSuffix(spec, "%") {
+		return spec[0 : len(spec)-1], dtSTRING, true
+	}
+	if strings.HasSuffix(spec, "{}") {
+		return spec[0 : len(spec)-2], dtSTRING, true
+	}
+	if n := len(spec); n > 3 && spec[n-3] == '{' && spec[n-1] == '}' {
+		if t, ok := typeLetters[spec[n-2]]; ok && t != dtREADFILE && t != dtWRITEFILE {
+			return spec[0 : n-3], t, true
+		}
+	}
+	return spec, dtSTRING, false
 }
 
 type actions map[string]option
 
 func parseAction(spec string, dest interface{}, actions map[string]option) error {
+	negatable := len(spec) > 1 && spec[len(spec)-1] == '!'
+	if negatable {
+		spec = spec[0 : len(spec)-1]
+	}
 	unary := false
 	var a actionType
-	var t dataType
-	if spec[len(spec)-1] == '+' {
+	var t, kt dataType
+	var sep, listSep byte
+	var minVals, maxVals int
+	var term string
+	minCount, maxCount := int64(math.MinInt64), int64(math.MaxInt64)
+	if ix := strings.Index(spec, "..."); ix != -1 {
+		a = atCAPTURE
+		spec, term = spec[:ix], spec[ix+3:]
+	} else if ix := strings.LastIndex(spec, "+{"); ix != -1 && strings.HasSuffix(spec, "}") {
+		unary = true
+		a = atINCREMENT
+		var err error
+		if minCount, maxCount, err = parseLimits(spec[ix+2 : len(spec)-1]); err != nil {
+			return fmt.Errorf("invalid spec, %s: %s", err, spec)
+		}
+		spec = spec[:ix]
+	} else if s, lo, hi, ok := repeatSuffix(strings.TrimSuffix(spec, "@")); ok {
+		a = atASSIGN
+		if strings.HasSuffix(spec, "@") {
+			a = atAPPEND
+		}
+		spec, minVals, maxVals = s, lo, hi
+		if minVals < 1 || (maxVals != -1 && maxVals < minVals) {
+			return fmt.Errorf("invalid spec, repeat counts must be {N}, {N,} or {N,M} with 0 < N <= M: %s", spec)
+		}
+	} else if n := len(spec); n > 2 && spec[n-2] == '@' && strings.IndexByte(listSeparators, spec[n-1]) != -1 {
+		a = atAPPEND
+		listSep = spec[n-1]
+		spec = spec[0 : n-2]
+	} else if n > 3 && spec[n-3] == '[' && spec[n-1] == ']' && strings.IndexByte(listSeparators, spec[n-2]) != -1 {
+		a = atAPPEND
+		listSep = spec[n-2]
+		spec = spec[0 : n-3]
+	} else if spec[len(spec)-1] == '+' {
 		unary = true
 		a = atINCREMENT
 		spec = spec[0 : len(spec)-1]
 	} else if spec[len(spec)-1] == '@' {
 		a = atAPPEND
 		spec = spec[0 : len(spec)-1]
-	} else if spec[len(spec)-2:] == "[]" {
+		if s, k, ok := mapSuffix(spec); ok {
+			a = atMAPLIST
+			spec, kt = s, k
+		}
+	} else if strings.HasSuffix(spec, "[]") {
 		a = atAPPEND
 		spec = spec[0 : len(spec)-2]
-	} else if spec[len(spec)-1] == '%' {
-		a = atMAP
-		spec = spec[0 : len(spec)-1]
-	} else if spec[len(spec)-2:] == "{}" {
+	} else if s, k, ok := mapSuffix(spec); ok {
 		a = atMAP
-		spec = spec[0 : len(spec)-2]
+		spec, kt = s, k
+	} else if n := len(spec); n > 3 && spec[n-3] == '{' && spec[n-1] == '}' && strings.IndexByte(pathSeparators, spec[n-2]) != -1 {
+		a = atNESTED
+		sep = spec[n-2]
+		spec = spec[0 : n-3]
 	} else {
 		a = atASSIGN
 	}
 
-	switch spec[len(spec)-2:] {
+	var typeSuffix string
+	if len(spec) > 2 {
+		typeSuffix = spec[len(spec)-2:]
+	}
+	switch typeSuffix {
 	case "=s":
 		t = dtSTRING
 		spec = spec[0 : len(spec)-2]
@@ -134,50 +394,282 @@ func parseAction(spec string, dest interface{}, actions map[string]option) error
 	case "=f":
 		t = dtFLOAT
 		spec = spec[0 : len(spec)-2]
+	case "=r":
+		t = dtREADFILE
+		spec = spec[0 : len(spec)-2]
+	case "=w":
+		t = dtWRITEFILE
+		spec = spec[0 : len(spec)-2]
+	case "=*":
+		t = dtAUTO
+		spec = spec[0 : len(spec)-2]
 	default:
 		if a == atINCREMENT {
 			t = dtINTEGER
+			unary = true
+		} else if a == atCAPTURE {
+			t = dtSTRING
 		} else {
 			t = dtBOOLEAN
+			unary = true
 		}
-		unary = true
 	}
 
-	if unary && a == atAPPEND {
-		return fmt.Errorf("invalid spec, using @ to parse repeated options, but not specifying type with either =i =s or =f: %s", spec)
+	if unary && minVals > 0 {
+		return fmt.Errorf("invalid spec, repeat counts require a type of either =i =s =f =r or =w: %s", spec)
+	}
+
+	if unary && (a == atAPPEND || a == atMAPLIST) {
+		return fmt.Errorf("invalid spec, using @ to parse repeated options, but not specifying type with either =i =s =f =r or =w: %s", spec)
+	}
+
+	if a == atCAPTURE && t != dtSTRING {
+		return fmt.Errorf("invalid spec, options capturing arguments with ... only hold strings: %s", spec)
+	}
+
+	if a == atNESTED && (t == dtREADFILE || t == dtWRITEFILE || unary) {
+		return fmt.Errorf("invalid spec, nested maps require a type of either =* =i =s or =f: %s", spec)
+	}
+
+	if t == dtAUTO && a != atNESTED {
+		return fmt.Errorf("invalid spec, =* is only valid for nested maps like name=*{.}: %s", spec)
 	}
 
-	optionNames := strings.Split(spec, "|")
-	name := optionNames[len(optionNames)-1]
-	for _, opt := range optionNames {
-		var dashName string
+	var name string
+	aliases := make([]string, 0)
+	decr := make([]string, 0)
+	for _, opt := range strings.Split(spec, "|") {
+		decrement := len(opt) > 1 && opt[0] == '-'
+		if decrement {
+			opt = opt[1:]
+		}
+		dashName := "--" + opt
 		if len(opt) == 1 {
 			dashName = "-" + opt
+		}
+		if decrement {
+			decr = append(decr, dashName)
 		} else {
-			dashName = "--" + opt
+			name = opt
+			aliases = append(aliases, dashName)
 		}
+	}
+	if len(decr) > 0 && a != atINCREMENT {
+		return fmt.Errorf("invalid spec, decrement aliases like -name are only valid for counters with +: %s", spec)
+	}
+	if len(aliases) == 0 {
+		return fmt.Errorf("invalid spec, counters need at least one alias that is not a decrement: %s", spec)
+	}
+	var neg []string
+	if negatable {
+		neg = negatedAliases(aliases)
+	}
+
+	o := option{
+		name:     name,
+		unary:    unary,
+		dest:     reflect.ValueOf(dest),
+		action:   a,
+		dataType: t,
+		keyType:  kt,
+		pathSep:  sep,
+		listSep:  listSep,
+		minVals:  minVals,
+		maxVals:  maxVals,
+		term:     term,
+		minCount: minCount,
+		maxCount: maxCount,
+		aliases:  aliases,
+		decr:     decr,
+		neg:      neg,
+		meta:     &OptionMeta{},
+	}
+	if err := checkCallback(dest, o.valueType()); err != nil {
+		return fmt.Errorf("invalid callback for %s: %s", spec, err)
+	}
+	if err := o.checkMapDest(); err != nil {
+		return fmt.Errorf("invalid destination for %s: %s", spec, err)
+	}
+	if o.dest.Kind() == reflect.Ptr {
+		o.dflt = copyValue(o.dest.Elem())
+	}
+
+	if negatable && !o.canNegate() {
+		return fmt.Errorf("invalid spec, only flags, lists and maps can be negated with !: %s", spec)
+	}
+	if negatable && len(neg) == 0 {
+		return fmt.Errorf("invalid spec, negating with ! needs a long alias for --no-NAME: %s", spec)
+	}
+	if negatable && !unary && o.dest.Kind() == reflect.Func {
+		return fmt.Errorf("invalid spec, lists and maps negated with ! cannot use callbacks: %s", spec)
+	}
+
+	for _, dashName := range append(append(aliases, decr...), neg...) {
 		if _, ok := actions[dashName]; ok {
 			return fmt.Errorf("invalid option spec: %s is not unique from %s", dashName, spec)
 		}
-		actions[dashName] = option{name, unary, reflect.ValueOf(dest), a, t}
+		actions[dashName] = o
+	}
+	return nil
+}
+
+// checkCallback verifies that a function destination is one of the
+// supported callback signatures: it may take no arguments, the value,
+// or the option name and the value, and may return an error.  The
+// value argument must accept values of type value.
+func checkCallback(dest interface{}, value reflect.Type) error {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Func {
+		return nil
+	}
+	switch t.NumIn() {
+	case 0, 1:
+	case 2:
+		if t.In(0).Kind() != reflect.String {
+			return fmt.Errorf("first argument must be a string for the option name, got %s", t.In(0))
+		}
+	default:
+		return fmt.Errorf("expected at most 2 arguments, got %d", t.NumIn())
+	}
+	if n := t.NumIn(); n > 0 && !value.AssignableTo(t.In(n-1)) {
+		if value == keyValType {
+			return fmt.Errorf("value argument must be an interface{} for map options, got %s", t.In(n-1))
+		}
+		return fmt.Errorf("value argument must accept %s, got %s", value, t.In(n-1))
+	}
+	switch t.NumOut() {
+	case 0:
+	case 1:
+		if t.Out(0) != errorType {
+			return fmt.Errorf("return value must be an error, got %s", t.Out(0))
+		}
+	default:
+		return fmt.Errorf("expected at most 1 return value, got %d", t.NumOut())
 	}
 	return nil
 }
 
-func increment(val reflect.Value) reflect.Value {
-	return reflect.ValueOf(val.Int() + 1)
+func push(arr reflect.Value, val interface{}) (reflect.Value, error) {
+	// The value type may not be the same as the array value type
+	// so try to convert the passed in value to the array value type
+	rVal, err := valueOf(val, arr.Type().Elem())
+	if err != nil {
+		return arr, err
+	}
+	return reflect.Append(arr, rVal), nil
 }
 
-func push(arr reflect.Value, val interface{}) reflect.Value {
+// valueOf returns a reflect.Value for val converted to type t.  Numbers
+// are checked for overflow like Lookup does, and an error is returned
+// rather than storing a truncated or wrapped value.
+func valueOf(val interface{}, t reflect.Type) (reflect.Value, error) {
 	rVal := reflect.ValueOf(val)
-	if rVal.Type() != arr.Type().Elem() {
-		// The value type is not the same as the array value type
-		// so try to convert the passed in value to the array value type
-		newValPtr := reflect.New(arr.Type().Elem())
-		newValPtr.Elem().Set(rVal.Convert(arr.Type().Elem()))
-		rVal = newValPtr.Elem()
+	if k := rVal.Kind(); k == t.Kind() && (k == reflect.String || k == reflect.Bool) {
+		return rVal.Convert(t), nil
+	}
+	if converted, ok := convertValue(rVal, t); ok {
+		return converted, nil
+	}
+	return rVal, fmt.Errorf("cannot store %v in %s", val, t)
+}
+
+// setMap stores the value under the key in the map m, converting both
+// to the map types.
+func setMap(m reflect.Value, kv keyVal) error {
+	key, err := valueOf(kv.key, m.Type().Key())
+	if err != nil {
+		return err
+	}
+	val, err := valueOf(kv.val, m.Type().Elem())
+	if err != nil {
+		return err
+	}
+	m.SetMapIndex(key, val)
+	return nil
+}
+
+// pushMap appends the value to the slice stored under the key in the
+// map m, creating the slice if needed.
+func pushMap(m reflect.Value, kv keyVal) error {
+	key, err := valueOf(kv.key, m.Type().Key())
+	if err != nil {
+		return err
+	}
+	list := m.MapIndex(key)
+	if !list.IsValid() {
+		list = reflect.MakeSlice(m.Type().Elem(), 0, 1)
+	}
+	if list, err = push(list, kv.val); err != nil {
+		return err
+	}
+	m.SetMapIndex(key, list)
+	return nil
+}
+
+// fits reports whether values of the data type can be stored in a
+// destination of type dst.
+func (t dataType) fits(dst reflect.Type) bool {
+	if t.goType().AssignableTo(dst) {
+		return true
 	}
-	return reflect.Append(arr, rVal)
+	switch t {
+	case dtSTRING:
+		return dst.Kind() == reflect.String
+	case dtINTEGER:
+		return isSigned(dst.Kind()) || isUnsigned(dst.Kind())
+	case dtFLOAT:
+		return isFloat(dst.Kind())
+	case dtBOOLEAN:
+		return dst.Kind() == reflect.Bool
+	}
+	return false
+}
+
+// checkMapDest verifies that a pointer to a map destination can hold
+// the keys and values of a map option.
+func (o *option) checkMapDest() error {
+	if o.dest.Kind() != reflect.Ptr || !o.isMap() {
+		return nil
+	}
+	t := o.dest.Elem().Type()
+	if o.action == atNESTED {
+		if t != reflect.TypeOf(map[string]interface{}(nil)) {
+			return fmt.Errorf("nested maps must be stored in a *map[string]interface{}, not %s", o.dest.Type())
+		}
+		return nil
+	}
+	if t.Kind() != reflect.Map {
+		return fmt.Errorf("destination must be a pointer to a map, not %s", o.dest.Type())
+	}
+	if !o.keyType.fits(t.Key()) {
+		return fmt.Errorf("%s keys cannot be stored in %s", o.keyType, t)
+	}
+	elem := t.Elem()
+	if o.action == atMAPLIST {
+		if elem.Kind() != reflect.Slice {
+			return fmt.Errorf("destination must be a map of slices, not %s", t)
+		}
+		elem = elem.Elem()
+	}
+	if !o.dataType.fits(elem) {
+		return fmt.Errorf("%s values cannot be stored in %s", o.dataType, t)
+	}
+	return nil
+}
+
+// goType returns the type used to store values of the data type.
+func (t dataType) goType() reflect.Type {
+	switch t {
+	case dtSTRING:
+		return reflect.TypeOf("")
+	case dtINTEGER:
+		return reflect.TypeOf(int64(0))
+	case dtFLOAT:
+		return reflect.TypeOf(float64(0))
+	case dtREADFILE, dtWRITEFILE:
+		return reflect.TypeOf((*os.File)(nil))
+	}
+	return reflect.TypeOf(false)
 }
 
 func (o *OptionParser) initResultKey(opt *option) {
@@ -185,52 +677,132 @@ func (o *OptionParser) initResultKey(opt *option) {
 		return
 	}
 	var dflt interface{}
-	if opt.unary {
-		if opt.action == atINCREMENT {
-			dflt = int64(0)
-		} else {
-			dflt = false
-		}
-	} else {
-		if opt.action == atAPPEND {
-			switch opt.dataType {
-			case dtSTRING:
-				dflt = make([]string, 0)
-			case dtINTEGER:
-				dflt = make([]int64, 0)
-			case dtFLOAT:
-				dflt = make([]float64, 0)
-			}
-		} else if opt.action == atMAP {
-			switch opt.dataType {
-			case dtSTRING:
-				dflt = make(map[string]string)
-			case dtINTEGER:
-				dflt = make(map[string]int64)
-			case dtFLOAT:
-				dflt = make(map[string]float64)
-			}
-		} else {
-			switch opt.dataType {
-			case dtSTRING:
-				dflt = ""
-			case dtINTEGER:
-				dflt = int64(0)
-			case dtFLOAT:
-				dflt = float64(0)
-			}
-		}
+	valType := opt.dataType.goType()
+	if opt.takesValues() {
+		valType = reflect.SliceOf(valType)
+	}
+	switch opt.action {
+	case atINCREMENT:
+		dflt = int64(0)
+	case atAPPEND, atCAPTURE:
+		dflt = reflect.MakeSlice(reflect.SliceOf(valType), 0, 0).Interface()
+	case atMAP:
+		dflt = reflect.MakeMap(reflect.MapOf(opt.keyType.goType(), valType)).Interface()
+	case atMAPLIST:
+		dflt = reflect.MakeMap(reflect.MapOf(opt.keyType.goType(), reflect.SliceOf(valType))).Interface()
+	case atNESTED:
+		dflt = make(map[string]interface{})
+	default:
+		dflt = reflect.Zero(valType).Interface()
 	}
 	o.Results[opt.name] = dflt
 }
 
+// Occurrence records a single option or positional argument seen by
+// one of the Process routines.
+type Occurrence struct {
+	// Alias is the option alias used, such as "-v" or "--verbose".  It
+	// is empty for positional arguments and is the variable name, such
+	// as "$MYAPP_VERBOSE", for options set from the environment.
+	Alias string
+
+	// Name is the canonical name of the option, the key used in
+	// OptionParser.Results.  It is empty for positional arguments.
+	Name string
+
+	// Raw is the unparsed option value, or the positional argument.
+	Raw string
+
+	// Value is the parsed option value.  It is false for negated flags
+	// like --no-verbose and nil for negated lists and maps.
+	Value interface{}
+
+	// Index is the position of the argument in the processed arguments,
+	// or -1 for options set from the environment.
+	Index int
+}
+
 // OptionParser struct will contain the `Results` and `Args` after
 // one of the Process routines is called.  A OptionParser object
 // is created with either NewParser or NewDirectAssignParser
 type OptionParser struct {
 	actions actions
-	Results map[string]interface{}
-	Args    []string
+	shared  bool
+
+	// files are the files opened for =r and =w options, closed by
+	// Reset.  pending holds the occurrences of file options waiting
+	// for their files to be opened once all arguments are parsed.
+	files   []*os.File
+	pending []pendingFile
+
+	// seen holds the names of the options given to the last call of
+	// one of the Process routines.
+	seen map[string]bool
+
+	// first maps option names to the index in Occurrences of their
+	// first occurrence on the command line, for the repeat policy.
+	first map[string]int
+
+	// defaults is a copy of Results taken before the first option was
+	// processed, restored by Reset.
+	defaults map[string]interface{}
+	Results  map[string]interface{}
+	Args     []string
+
+	// Occurrences records every option and positional argument in the
+	// order they were seen by the last call to one of the Process
+	// routines.  Unlike Results, repeated options are not collapsed.
+	Occurrences []Occurrence
+
+	// ExpandArgsFiles enables response file expansion.  When set, any
+	// `@path` argument before the first `--` is replaced with the
+	// arguments read from path prior to processing.  The file is split
+	// into arguments using shell-like quoting rules and may itself
+	// contain `@path` arguments.
+	ExpandArgsFiles bool
+
+	// NegativeNumbers allows arguments like "-5" or "-1.5" to be treated
+	// as positional arguments rather than unknown options.  An argument
+	// that exactly matches a registered option is always an option, and
+	// if any numeric short option (such as "-1") is registered then
+	// numbers are never treated specially since they would be ambiguous.
+	NegativeNumbers bool
+
+	// MapSeparators are the characters that separate the key from the
+	// value for map options, "=" if empty.  The first separator found is
+	// used, and a separator can be included in a key by escaping it with
+	// a backslash.  Backslashes are only special when MapSeparators is
+	// set, so with the default "=" a key like `\\host\share` is kept as
+	// given.
+	MapSeparators string
+
+	// MapKeyOnly allows map option values without a separator, giving the
+	// key an empty value: "" for strings, 0 for numbers and true for the
+	// inferred values of =* nested maps, so `--set debug` works like a
+	// flag.  Otherwise a missing separator is an error, as it always is
+	// for =r and =w values.
+	MapKeyOnly bool
+
+	// RequireOrder stops option processing at the first non-option
+	// argument.  That argument and everything following it is left
+	// untouched in OptionParser.Args.  This is useful for wrappers
+	// like `mytool run cmd --flag-for-cmd`.
+	RequireOrder bool
+
+	// PosixlyCorrect enables RequireOrder when the POSIXLY_CORRECT
+	// environment variable is set.
+	PosixlyCorrect bool
+
+	// Repeat is the RepeatPolicy for options holding a single value
+	// that are given more than once.  It can be overridden for each
+	// option with OptionMeta.Repeat.  The last value wins by default.
+	Repeat RepeatPolicy
+
+	// Positional, if set, is called with each non-option argument in
+	// the order they are encountered instead of collecting them in
+	// OptionParser.Args.  Any error returned will abort processing.
+	// Arguments following `--` are still stored in OptionParser.Args.
+	Positional func(arg string) error
 }
 
 // NewParser generates an OptionParser object from the opts passed in.
@@ -244,7 +816,7 @@ func NewParser(opts []string) OptionParser {
 		}
 	}
 	results := make(map[string]interface{})
-	return OptionParser{actions, results, nil}
+	return OptionParser{actions: actions, Results: results}
 }
 
 // NewDirectAssignParser generates an OptionParser object from the `opts` passed in.
@@ -257,72 +829,146 @@ func NewDirectAssignParser(opts map[string]interface{}) OptionParser {
 			panic(err)
 		}
 	}
-	return OptionParser{actions, nil, nil}
+	return OptionParser{actions: actions}
 }
 
 // ProcessAll will parse all arguments in args.  If there are any
 // arguments in args that start with '-' and are not known
 // options then an error will be returned.  Any non-options will
-// be available in OptionParser.Args.
+// be available in OptionParser.Args.  A lone "-" is not an option,
+// by convention it refers to stdin or stdout and is left in
+// OptionParser.Args like any other positional argument.  Options
+// not given in args are then set from the environment variables named
+// by OptionMeta.Env, and an error is returned if an option marked as
+// Required was still not given.  Files for =r and =w options are only
+// opened, and their callbacks called, once every argument has been
+// parsed without error, and every =r file is opened before any =w
+// file is created, so a mistake on the command line never truncates an
+// =w file.
 func (o *OptionParser) ProcessAll(args []string) error {
 	err := o.processSome(args)
-	if _, ok := err.(*dashDash); ok {
-		return nil
+	parsed := o.Args
+	if dd, ok := err.(*dashDash); ok {
+		parsed = o.Args[:dd.checked]
+		err = nil
 	}
 	if err != nil {
 		return err
 	} else {
-		for _, opt := range o.Args {
-			if len(opt) > 0 && opt[0] == '-' {
+		for _, opt := range parsed {
+			if o.isOption(opt) {
 				return fmt.Errorf("Unknown option: %s", opt)
 			}
 		}
 	}
-	return nil
+	if err := o.applyEnv(); err != nil {
+		return err
+	}
+	for _, info := range o.Options() {
+		if info.Required && !o.seen[info.Name] {
+			return fmt.Errorf("missing required option: %s", info.Aliases[len(info.Aliases)-1])
+		}
+	}
+	return o.openFiles()
 }
 
 // ProcessSome will parse all known arguments in args.  Any non-options
 // and unknown options will be available in OPtionParser.Args.  This
 // can be used to implement multple pass options parsing, for example
 // perhaps sub-commands options are parsed seperately from global options.
+// Like ProcessAll, options not given in args are set from their
+// OptionMeta.Env environment variables.
 func (o *OptionParser) ProcessSome(args []string) error {
 	err := o.processSome(args)
 	if _, ok := err.(*dashDash); ok {
-		return nil
+		err = nil
 	}
 	if err != nil {
 		return err
 	}
-	return nil
+	if err := o.applyEnv(); err != nil {
+		return err
+	}
+	return o.openFiles()
+}
+
+// ProcessString splits s into arguments with SplitArgs and then
+// parses them with ProcessAll.
+func (o *OptionParser) ProcessString(s string) error {
+	args, err := SplitArgs(s)
+	if err != nil {
+		return err
+	}
+	return o.ProcessAll(args)
 }
 
 func (o *OptionParser) processSome(args []string) error {
 	o.Args = make([]string, 0)
+	o.Occurrences = make([]Occurrence, 0)
+	o.seen = make(map[string]bool)
+	o.first = make(map[string]int)
+	o.pending = nil
+	if o.defaults == nil && o.Results != nil {
+		o.defaults = copyResults(o.Results)
+	}
+	if o.ExpandArgsFiles {
+		var err error
+		if args, err = expandArgsFiles(args); err != nil {
+			return err
+		}
+	}
+	total := len(args)
 	for len(args) > 0 {
+		index := total - len(args)
 		if args[0] == "--" {
-			o.Args = append(o.Args, args[1:]...)
-			return &dashDash{}
+			o.addArgs(index+1, args[1:]...)
+			return &dashDash{0}
 		}
 
 		var err error
 		if opt, ok := o.actions[args[0]]; ok {
+			occ := Occurrence{Alias: args[0], Name: opt.name, Index: index}
 			var value interface{}
-			if opt.unary {
-				value = true
+			if opt.negates(args[0]) {
+				if opt.unary {
+					value = false
+				}
+				args = args[1:]
+			} else if opt.unary {
+				value = !opt.decrements(args[0])
 				args = args[1:]
+			} else if opt.action == atCAPTURE {
+				vals, n, err := opt.capture(nil, args[1:])
+				if err != nil {
+					return err
+				}
+				value = vals
+				occ.Raw = QuoteArgs(vals)
+				args = args[1+n:]
+			} else if opt.takesValues() {
+				vals, n := o.collectValues(opt, nil, args[1:])
+				if value, err = o.parseValues(opt, vals); err != nil {
+					return err
+				}
+				occ.Raw = QuoteArgs(vals)
+				args = args[1+n:]
 			} else {
 				if len(args) < 2 {
 					return fmt.Errorf("missing argument value for option: --%s", opt.name)
 				} else {
-					if value, err = opt.parseValue(args[1]); err != nil {
+					if value, err = o.parseValue(opt, args[1]); err != nil {
 						return err
 					}
 				}
+				occ.Raw = args[1]
 				args = args[2:]
 			}
-			o.setParsedOption(opt, value)
+			occ.Value = value
+			if err := o.setParsedOption(opt, occ); err != nil {
+				return o.stopped(err, total, args)
+			}
 		} else {
-			if len(args[0]) > 0 && args[0][0] == '-' {
+			if o.isOption(args[0]) {
 				var arg, val string
 				if args[0][1] != '-' {
 					arg = args[0][0:2]
@@ -336,20 +982,57 @@ func (o *OptionParser) processSome(args []string) error {
 				}
 				if opt, ok := o.actions[arg]; ok {
 					var value interface{} = true
+					raw := val
 					if len(val) <= 0 {
 						return fmt.Errorf("missing argument value for option: --%s", opt.name)
+					} else if opt.decrements(arg) || opt.negates(arg) || (opt.action == atINCREMENT && opt.dest.Kind() == reflect.Func) {
+						// counter callbacks are called once per increment
+						// so they cannot be set to a value either
+						return fmt.Errorf("option %s does not take a value", arg)
+					} else if opt.action == atCAPTURE {
+						vals, n, err := opt.capture([]string{val}, args[1:])
+						if err != nil {
+							return err
+						}
+						value = vals
+						raw = QuoteArgs(vals)
+						// drop the captured args, the option itself is dropped below
+						args = args[n:]
+					} else if opt.takesValues() {
+						vals, n := o.collectValues(opt, []string{val}, args[1:])
+						if value, err = o.parseValues(opt, vals); err != nil {
+							return err
+						}
+						raw = QuoteArgs(vals)
+						// drop the values, the option itself is dropped below
+						args = args[n:]
 					} else {
-						if value, err = opt.parseValue(val); err != nil {
+						if value, err = o.parseValue(opt, val); err != nil {
 							return err
 						}
 					}
-					o.setParsedOption(opt, value)
+					occ := Occurrence{Alias: arg, Name: opt.name, Raw: raw, Value: value, Index: index}
+					if err := o.setParsedOption(opt, occ); err != nil {
+						return o.stopped(err, total, args[1:])
+					}
 				} else {
-					o.Args = append(o.Args, args[0])
+					o.addArgs(index, args[0])
 				}
 				args = args[1:]
 			} else {
-				o.Args = append(o.Args, args[0])
+				if o.requireOrder() {
+					parsed := len(o.Args)
+					o.addArgs(index, args...)
+					return &dashDash{parsed}
+				}
+				if o.Positional != nil {
+					o.Occurrences = append(o.Occurrences, Occurrence{Raw: args[0], Index: index})
+					if err := o.Positional(args[0]); err != nil {
+						return o.stopped(err, total, args[1:])
+					}
+				} else {
+					o.addArgs(index, args[0])
+				}
 				args = args[1:]
 			}
 		}
@@ -357,7 +1040,97 @@ func (o *OptionParser) processSome(args []string) error {
 	return nil
 }
 
-func (o *OptionParser) setParsedOption(opt option, value interface{}) {
+// stopped saves the unprocessed args in OptionParser.Args when a
+// callback has returned ErrStop, or an error wrapping it.  The rest of
+// the arguments are the tail of the total arguments processed.
+func (o *OptionParser) stopped(err error, total int, rest []string) error {
+	if errors.Is(err, ErrStop) {
+		o.addArgs(total-len(rest), rest...)
+	}
+	return err
+}
+
+// addArgs appends args to OptionParser.Args, recording each as a
+// positional Occurrence starting at argument index.
+func (o *OptionParser) addArgs(index int, args ...string) {
+	for i, arg := range args {
+		o.Args = append(o.Args, arg)
+		o.Occurrences = append(o.Occurrences, Occurrence{Raw: arg, Index: index + i})
+	}
+}
+
+func (o *OptionParser) requireOrder() bool {
+	if o.RequireOrder {
+		return true
+	}
+	if o.PosixlyCorrect {
+		_, ok := os.LookupEnv("POSIXLY_CORRECT")
+		return ok
+	}
+	return false
+}
+
+// isOption reports whether arg should be processed as an option
+// rather than as a positional argument.
+func (o *OptionParser) isOption(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	if o.NegativeNumbers && isNumber(arg) && !o.hasNumericShortOption() {
+		return false
+	}
+	return true
+}
+
+func (o *OptionParser) hasNumericShortOption() bool {
+	for name := range o.actions {
+		if len(name) == 2 && name[1] >= '0' && name[1] <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(arg string) bool {
+	if len(arg) < 2 || (arg[1] != '.' && (arg[1] < '0' || arg[1] > '9')) {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+func (o *OptionParser) setParsedOption(opt option, occ Occurrence) error {
+	o.seen[opt.name] = true
+	apply, err := o.checkRepeat(opt, occ)
+	o.Occurrences = append(o.Occurrences, occ)
+	if !apply {
+		return err
+	}
+	if opt.isFile() {
+		o.pending = append(o.pending, pendingFile{opt, len(o.Occurrences) - 1})
+		return nil
+	}
+	return o.applyOccurrence(opt, occ)
+}
+
+// applyOccurrence stores the value of occ for opt.
+func (o *OptionParser) applyOccurrence(opt option, occ Occurrence) error {
+	if opt.negates(occ.Alias) && !opt.unary {
+		o.clear(opt)
+		return nil
+	}
+	if opt.listSep != 0 {
+		for _, value := range occ.Value.([]interface{}) {
+			if err := o.applyValue(opt, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return o.applyValue(opt, occ.Value)
+}
+
+func (o *OptionParser) applyValue(opt option, value interface{}) error {
 	if opt.dest.IsValid() {
 		if opt.dest.Kind() == reflect.Func {
 			t := reflect.TypeOf(opt.dest.Interface())
@@ -370,32 +1143,78 @@ func (o *OptionParser) setParsedOption(opt option, value interface{}) {
 				cbArgs[0] = reflect.ValueOf(opt.name)
 				cbArgs[1] = reflect.ValueOf(value)
 			}
-			opt.dest.Call(cbArgs)
+			out := opt.dest.Call(cbArgs)
+			if len(out) == 1 && !out[0].IsNil() {
+				err := out[0].Interface().(error)
+				if errors.Is(err, ErrStop) {
+					return err
+				}
+				return fmt.Errorf("option --%s: %w", opt.name, err)
+			}
 		} else {
 			switch opt.action {
 			case atINCREMENT:
-				opt.dest.Elem().Set(increment(opt.dest.Elem()))
+				opt.dest.Elem().Set(opt.count(opt.dest.Elem(), value))
 			case atAPPEND:
-				opt.dest.Elem().Set(push(opt.dest.Elem(), value))
+				list, err := push(opt.dest.Elem(), value)
+				if err != nil {
+					return fmt.Errorf("option --%s: %s", opt.name, err)
+				}
+				opt.dest.Elem().Set(list)
 			case atMAP:
-				kv := value.(keyVal)
-				opt.dest.Elem().SetMapIndex(reflect.ValueOf(kv.key), reflect.ValueOf(kv.val))
-			case atASSIGN:
-				opt.dest.Elem().Set(reflect.ValueOf(value))
+				if err := setMap(opt.dest.Elem(), value.(keyVal)); err != nil {
+					return fmt.Errorf("option --%s: %s", opt.name, err)
+				}
+			case atMAPLIST:
+				if opt.dest.Elem().IsNil() {
+					opt.dest.Elem().Set(reflect.MakeMap(opt.dest.Elem().Type()))
+				}
+				if err := pushMap(opt.dest.Elem(), value.(keyVal)); err != nil {
+					return fmt.Errorf("option --%s: %s", opt.name, err)
+				}
+			case atNESTED:
+				m, _ := opt.dest.Elem().Interface().(map[string]interface{})
+				if m == nil {
+					m = make(map[string]interface{})
+					opt.dest.Elem().Set(reflect.ValueOf(m))
+				}
+				if err := opt.setNested(m, value.(keyVal)); err != nil {
+					return err
+				}
+			case atASSIGN, atCAPTURE:
+				val, err := valueOf(value, opt.dest.Elem().Type())
+				if err != nil {
+					return fmt.Errorf("option --%s: %s", opt.name, err)
+				}
+				opt.dest.Elem().Set(val)
 			}
 		}
 	} else {
 		o.initResultKey(&opt)
 		switch opt.action {
 		case atINCREMENT:
-			o.Results[opt.name] = increment(reflect.ValueOf(o.Results[opt.name])).Interface()
+			o.Results[opt.name] = opt.count(reflect.ValueOf(o.Results[opt.name]), value).Interface()
 		case atAPPEND:
-			o.Results[opt.name] = push(reflect.ValueOf(o.Results[opt.name]), value).Interface()
+			list, err := push(reflect.ValueOf(o.Results[opt.name]), value)
+			if err != nil {
+				return fmt.Errorf("option --%s: %s", opt.name, err)
+			}
+			o.Results[opt.name] = list.Interface()
 		case atMAP:
-			kv := value.(keyVal)
-			reflect.ValueOf(o.Results[opt.name]).SetMapIndex(reflect.ValueOf(kv.key), reflect.ValueOf(kv.val))
-		case atASSIGN:
+			if err := setMap(reflect.ValueOf(o.Results[opt.name]), value.(keyVal)); err != nil {
+				return fmt.Errorf("option --%s: %s", opt.name, err)
+			}
+		case atMAPLIST:
+			if err := pushMap(reflect.ValueOf(o.Results[opt.name]), value.(keyVal)); err != nil {
+				return fmt.Errorf("option --%s: %s", opt.name, err)
+			}
+		case atNESTED:
+			if err := opt.setNested(o.Results[opt.name].(map[string]interface{}), value.(keyVal)); err != nil {
+				return err
+			}
+		case atASSIGN, atCAPTURE:
 			o.Results[opt.name] = reflect.ValueOf(value).Interface()
 		}
 	}
+	return nil
 }
@@ -1,13 +1,17 @@
 package optigo
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
 
 func TestParseValue(t *testing.T) {
 	// test fail to parse int from string
-	o := option{"test", false, reflect.ValueOf(nil), atASSIGN, dtINTEGER}
+	o := option{name: "test", dest: reflect.ValueOf(nil), action: atASSIGN, dataType: dtINTEGER}
 	if _, err := o.parseValue("abc"); err == nil {
 		t.Fail()
 	}
@@ -73,6 +77,22 @@ func TestProcesFailure(t *testing.T) {
 	}
 }
 
+func TestDashDashUnknownOption(t *testing.T) {
+	op := NewParser([]string{"v|verbose+"})
+
+	// once -- is seen unknown options are left in Args, not reported
+	if err := op.ProcessAll([]string{"--bogus", "--", "--other"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"--bogus", "--other"}) {
+		t.Errorf("unexpected args: %q", op.Args)
+	}
+
+	if err := op.ProcessAll([]string{"-v", "--", "--other"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDashDash(t *testing.T) {
 	foobar := false
 	op := NewDirectAssignParser(map[string]interface{}{
@@ -129,3 +149,949 @@ func TestSingleOptionInvalidArg(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestLoneDash(t *testing.T) {
+	op := NewParser([]string{
+		"i|input=s",
+	})
+
+	if err := op.ProcessAll([]string{"-", "--input", "-"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Args) != 1 || op.Args[0] != "-" {
+		t.Errorf("unexpected args: %#v", op.Args)
+	}
+	if op.Results["input"] != "-" {
+		t.Errorf("unexpected input: %#v", op.Results["input"])
+	}
+}
+
+func TestFileOptions(t *testing.T) {
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "in")
+	outPath := filepath.Join(dir, "out")
+	if err := os.WriteFile(inPath, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var in, out, stdin *os.File
+	op := NewDirectAssignParser(map[string]interface{}{
+		"i|input=r":  &in,
+		"o|output=w": &out,
+		"stdin=r":    &stdin,
+	})
+
+	if err := op.ProcessAll([]string{"-i", inPath, "--output=" + outPath, "--stdin", "-"}); err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	defer out.Close()
+
+	if in.Name() != inPath || out.Name() != outPath {
+		t.Errorf("unexpected files: %s %s", in.Name(), out.Name())
+	}
+	if stdin != os.Stdin {
+		t.Errorf("expected - to be stdin")
+	}
+
+	op = NewParser([]string{"i|input=r"})
+	if err := op.ProcessAll([]string{"-i", filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected error opening missing file")
+	}
+}
+
+func TestNegativeNumbers(t *testing.T) {
+	op := NewParser([]string{
+		"o|offset=i",
+		"d|delta=f",
+	})
+	op.NegativeNumbers = true
+
+	args := []string{"--offset", "-5", "--delta=-1.5", "-3", "-.5"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["offset"] != int64(-5) || op.Results["delta"] != float64(-1.5) {
+		t.Errorf("unexpected results: %#v", op.Results)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"-3", "-.5"}) {
+		t.Errorf("unexpected args: %#v", op.Args)
+	}
+
+	// without NegativeNumbers the positional looks like an unknown option
+	op = NewParser([]string{"o|offset=i"})
+	if err := op.ProcessAll([]string{"-3"}); err == nil {
+		t.Fail()
+	}
+
+	// numeric short options take precedence, so numbers are not special
+	op = NewParser([]string{"1|one", "o|offset=i"})
+	op.NegativeNumbers = true
+	if err := op.ProcessAll([]string{"-1", "-3"}); err == nil {
+		t.Fail()
+	}
+	if op.Results["one"] != true {
+		t.Errorf("expected -1 to be an option")
+	}
+}
+
+func TestPosixlyCorrect(t *testing.T) {
+	t.Setenv("POSIXLY_CORRECT", "1")
+
+	op := NewParser([]string{"v|verbose+"})
+	if err := op.ProcessAll([]string{"run", "-v"}); err != nil || op.Results["verbose"] != int64(1) {
+		t.Errorf("expected -v after positional to be processed without PosixlyCorrect")
+	}
+
+	op = NewParser([]string{"v|verbose+"})
+	op.PosixlyCorrect = true
+	if err := op.ProcessAll([]string{"-v", "run", "-v", "--bogus"}); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["verbose"] != int64(1) {
+		t.Errorf("unexpected verbose: %#v", op.Results["verbose"])
+	}
+	if !reflect.DeepEqual(op.Args, []string{"run", "-v", "--bogus"}) {
+		t.Errorf("unexpected args: %#v", op.Args)
+	}
+}
+
+func TestBogusCallback(t *testing.T) {
+	for _, cb := range []interface{}{
+		func(a, b, c interface{}) {},
+		func(a int, b interface{}) {},
+		func() string { return "" },
+		func() (error, error) { return nil, nil },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic for %T", cb)
+				}
+			}()
+			NewDirectAssignParser(map[string]interface{}{
+				"opt=s": cb,
+			})
+		}()
+	}
+}
+
+func TestCallbackErrorWrapped(t *testing.T) {
+	errBad := errors.New("bad value")
+	op := NewDirectAssignParser(map[string]interface{}{
+		"o|opt=s": func(name string, value interface{}) error {
+			return errBad
+		},
+	})
+	err := op.ProcessAll([]string{"--opt=x"})
+	if !errors.Is(err, errBad) || err.Error() != "option --opt: bad value" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestOccurrencesStop(t *testing.T) {
+	op := NewDirectAssignParser(map[string]interface{}{
+		"n|name=s": func(string) error { return ErrStop },
+		"v":        func() {},
+	})
+	if err := op.ProcessAll([]string{"a", "-v", "--name", "x", "b", "--", "c"}); err != ErrStop {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	indexes := make([]int, 0)
+	for _, occ := range op.Occurrences {
+		indexes = append(indexes, occ.Index)
+	}
+	if !reflect.DeepEqual(indexes, []int{0, 1, 2, 4, 5, 6}) {
+		t.Errorf("unexpected indexes: %v", indexes)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"a", "b", "--", "c"}) {
+		t.Errorf("unexpected args: %v", op.Args)
+	}
+}
+
+func TestWrappedErrStop(t *testing.T) {
+	op := NewDirectAssignParser(map[string]interface{}{
+		"h|help": func() error { return fmt.Errorf("help shown: %w", ErrStop) },
+	})
+	err := op.ProcessAll([]string{"--help", "a", "b"})
+	if !errors.Is(err, ErrStop) || err.Error() != "help shown: option processing stopped" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"a", "b"}) {
+		t.Errorf("unexpected args: %v", op.Args)
+	}
+}
+
+func TestMapListDirectAssign(t *testing.T) {
+	var ints map[string][]int64
+	floats := map[string][]float32{"x": {1}}
+	op := NewDirectAssignParser(map[string]interface{}{
+		"i|ints=i{}@":  &ints,
+		"f|floats=f%@": &floats,
+	})
+
+	args := []string{"-i", "a=1", "--ints=a=2", "-f", "x=1.5", "-f", "y=2"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ints, map[string][]int64{"a": {1, 2}}) {
+		t.Errorf("unexpected ints: %#v", ints)
+	}
+	if !reflect.DeepEqual(floats, map[string][]float32{"x": {1, 1.5}, "y": {2}}) {
+		t.Errorf("unexpected floats: %#v", floats)
+	}
+}
+
+func TestMapValueErrors(t *testing.T) {
+	op := NewParser([]string{"s|stropt=s%", "i|intopt=i%"})
+
+	// a missing = is an error rather than a panic
+	err := op.ProcessAll([]string{"--stropt", "abc"})
+	if err == nil || err.Error() != "invalid value for option --stropt, expected key=value: abc" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	op.MapKeyOnly = true
+	if err := op.ProcessAll([]string{"--stropt", "abc", "-i", "def"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["stropt"], map[string]string{"abc": ""}) || !reflect.DeepEqual(op.Results["intopt"], map[string]int64{"def": 0}) {
+		t.Errorf("unexpected results: %#v", op.Results)
+	}
+}
+
+func TestMapSeparators(t *testing.T) {
+	op := NewParser([]string{"s|stropt=s%"})
+	op.MapSeparators = ":="
+
+	args := []string{"-s", "a:1", "-s", "b=2", "-s", `c\=d\\=e=f`, "-s", `x\y=z`, "-s", "url=http://host"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"a": "1", "b": "2", `c=d\`: "e=f", `x\y`: "z", "url": "http://host"}
+	if !reflect.DeepEqual(op.Results["stropt"], expected) {
+		t.Errorf("unexpected results: %#v", op.Results["stropt"])
+	}
+
+	// escaped keys survive a round trip through ToArgs
+	reparsed := NewParser([]string{"s|stropt=s%"})
+	reparsed.MapSeparators = ":="
+	if err := reparsed.ProcessAll(op.ToArgs()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reparsed.Results, op.Results) {
+		t.Errorf("unexpected results: %#v", reparsed.Results)
+	}
+
+	// backslashes are not escapes with the default separator
+	op = NewParser([]string{"s|stropt=s%"})
+	if err := op.ProcessAll([]string{"-s", `\\host\share=x`}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["stropt"], map[string]string{`\\host\share`: "x"}) {
+		t.Errorf("unexpected results: %#v", op.Results["stropt"])
+	}
+	if args := op.ToArgs(); !reflect.DeepEqual(args, []string{"--stropt", `\\host\share=x`}) {
+		t.Errorf("unexpected args: %q", args)
+	}
+}
+
+func TestTypedMapKeys(t *testing.T) {
+	op := NewParser([]string{"w|weight=f{i}", "s|scale=s{f}@"})
+
+	args := []string{"-w", "3=0.5", "-w", "-1=2", "-s", "0.5=half", "-s", "0.5=mid"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["weight"], map[int64]float64{3: 0.5, -1: 2}) {
+		t.Errorf("unexpected weight: %#v", op.Results["weight"])
+	}
+	if !reflect.DeepEqual(op.Results["scale"], map[float64][]string{0.5: {"half", "mid"}}) {
+		t.Errorf("unexpected scale: %#v", op.Results["scale"])
+	}
+
+	// keys are parsed with the key type
+	if err := op.ProcessAll([]string{"-w", "x=0.5"}); err == nil {
+		t.Errorf("expected error for non-integer key")
+	}
+}
+
+func TestNestedMap(t *testing.T) {
+	var settings map[string]interface{}
+	op := NewDirectAssignParser(map[string]interface{}{
+		"s|set=s{/}": &settings,
+	})
+
+	if err := op.ProcessAll([]string{"-s", "a/b=1", "-s", "a/c=x", "-s", "d=2"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"a": map[string]interface{}{"b": "1", "c": "x"},
+		"d": "2",
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("unexpected settings: %#v", settings)
+	}
+
+	// a path holding nested keys cannot be given a value
+	if err := op.ProcessAll([]string{"-s", "a=1"}); err == nil {
+		t.Errorf("expected conflict error")
+	}
+
+	// reset restores the original nil map rather than sharing nested maps
+	op.Reset()
+	if settings != nil {
+		t.Errorf("unexpected settings after reset: %#v", settings)
+	}
+
+	for _, spec := range []string{"x=*", "x=*%", "x{.}", "x=r{.}"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic for spec %s", spec)
+				}
+			}()
+			NewParser([]string{spec})
+		}()
+	}
+
+	// nested maps can only be stored in a map[string]interface{}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic for a map[string]string destination")
+			}
+		}()
+		NewDirectAssignParser(map[string]interface{}{"s=s{.}": &map[string]string{}})
+	}()
+
+	// keys without a value are true for inferred values
+	op = NewParser([]string{"set=*{.}"})
+	op.MapKeyOnly = true
+	if err := op.ProcessAll([]string{"--set", "log.debug"}); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]interface{}{"log": map[string]interface{}{"debug": true}}
+	if !reflect.DeepEqual(op.Results["set"], expected) {
+		t.Errorf("unexpected set: %#v", op.Results["set"])
+	}
+}
+
+func TestListSeparator(t *testing.T) {
+	op := NewParser([]string{"t|tags=s@,", "n|nums=i[;]", "r|ratios=f@:"})
+
+	args := []string{"-t", `a,'b,c',d\,e`, "-t", "f", "-n", "1;2", "-n", "3", "-r", "0.5:1"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	tags := []string{"a", "b,c", "d,e", "f"}
+	if !reflect.DeepEqual(op.Results["tags"], tags) {
+		t.Errorf("unexpected tags: %#v", op.Results["tags"])
+	}
+	if !reflect.DeepEqual(op.Results["nums"], []int64{1, 2, 3}) {
+		t.Errorf("unexpected nums: %#v", op.Results["nums"])
+	}
+	if !reflect.DeepEqual(op.Results["ratios"], []float64{0.5, 1}) {
+		t.Errorf("unexpected ratios: %#v", op.Results["ratios"])
+	}
+	if len(op.Occurrences) != 5 {
+		t.Errorf("expected 5 occurrences, got %d", len(op.Occurrences))
+	}
+
+	// ToArgs escapes separators so the values round trip
+	args = op.ToArgs()
+	expected := []string{
+		"--nums", "1", "--nums", "2", "--nums", "3", "--ratios", "0.5", "--ratios", "1",
+		"--tags", "a", "--tags", `b\,c`, "--tags", `d\,e`, "--tags", "f",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected args: %q", args)
+	}
+	op.Reset()
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["tags"], tags) {
+		t.Errorf("unexpected tags after round trip: %#v", op.Results["tags"])
+	}
+
+	for _, args := range [][]string{{"-t", "x,'y"}, {"-t", `x\`}, {"-n", "1;x"}} {
+		if err := op.ProcessAll(args); err == nil {
+			t.Errorf("expected error for %q", args)
+		}
+	}
+
+	// quotes only start a quoted value at the beginning of a value
+	op.Reset()
+	if err := op.ProcessAll([]string{"-t", `O'Brien,"a,b"c,\'x`}); err != nil {
+		t.Fatal(err)
+	}
+	tags = []string{"O'Brien", "a,bc", "'x"}
+	if !reflect.DeepEqual(op.Results["tags"], tags) {
+		t.Errorf("unexpected tags: %#v", op.Results["tags"])
+	}
+	args = op.ToArgs()
+	expected = []string{"--tags", "O'Brien", "--tags", `a\,bc`, "--tags", `\'x`}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected args: %q", args)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic for unary list spec")
+			}
+		}()
+		NewParser([]string{"t@,"})
+	}()
+
+	var ports []int
+	op = NewDirectAssignParser(map[string]interface{}{"p|ports=i@,": &ports})
+	if err := op.ProcessAll([]string{"-p", "80,443", "-p", "8080"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ports, []int{80, 443, 8080}) {
+		t.Errorf("unexpected ports: %#v", ports)
+	}
+}
+
+func TestRepeatCount(t *testing.T) {
+	op := NewParser([]string{"c|coords=f{2}", "r|rgb=i{3}@", "n|names=s{1,}", "p|pair=i{1,2}", "v|verbose"})
+
+	args := []string{"-c", "1.5", "-2", "-r", "1", "2", "3", "--rgb=4", "5", "6", "-n", "a", "b", "-v", "-p", "1", "x", "--names=c"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["coords"], []float64{1.5, -2}) {
+		t.Errorf("unexpected coords: %#v", op.Results["coords"])
+	}
+	if !reflect.DeepEqual(op.Results["rgb"], [][]int64{{1, 2, 3}, {4, 5, 6}}) {
+		t.Errorf("unexpected rgb: %#v", op.Results["rgb"])
+	}
+	// optional values stop at the next option or unparsable value
+	if !reflect.DeepEqual(op.Results["names"], []string{"c"}) {
+		t.Errorf("unexpected names: %#v", op.Results["names"])
+	}
+	if !reflect.DeepEqual(op.Results["pair"], []int64{1}) {
+		t.Errorf("unexpected pair: %#v", op.Results["pair"])
+	}
+	if !reflect.DeepEqual(op.Args, []string{"x"}) {
+		t.Errorf("unexpected args: %q", op.Args)
+	}
+	if occ := op.Occurrences[1]; occ.Raw != "1 2 3" || occ.Index != 3 {
+		t.Errorf("unexpected occurrence: %#v", occ)
+	}
+	if got := op.ToArgs(); !reflect.DeepEqual(got, []string{
+		"--coords", "1.5", "-2", "--names", "c", "--pair", "1",
+		"--rgb", "1", "2", "3", "--rgb", "4", "5", "6", "--verbose",
+	}) {
+		t.Errorf("unexpected ToArgs: %q", got)
+	}
+
+	for _, args := range [][]string{{"-c", "1"}, {"-c", "1", "x"}, {"-r", "1", "2"}} {
+		if err := op.ProcessAll(args); err == nil {
+			t.Errorf("expected error for %q", args)
+		}
+	}
+
+	var coords []float32
+	op = NewDirectAssignParser(map[string]interface{}{"c|coords=f{2}": &coords})
+	if err := op.ProcessAll([]string{"-c", "0.5", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(coords, []float32{0.5, 2}) {
+		t.Errorf("unexpected coords: %#v", coords)
+	}
+
+	for _, spec := range []string{"x{2}", "x=s{0}", "x=s{3,2}", "x=*{2}"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic for spec %s", spec)
+				}
+			}()
+			NewParser([]string{spec})
+		}()
+	}
+}
+
+func TestCapture(t *testing.T) {
+	op := NewParser([]string{"exec...;", "x|run...", "v|verbose"})
+
+	args := []string{"--exec", "grep", "-v", "--", "{}", ";", "file", "-v", "--run", "ssh", "-v", "host", "--", "ls"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	// options and `--` are captured literally
+	if !reflect.DeepEqual(op.Results["exec"], []string{"grep", "-v", "--", "{}"}) {
+		t.Errorf("unexpected exec: %#v", op.Results["exec"])
+	}
+	if !reflect.DeepEqual(op.Results["run"], []string{"ssh", "-v", "host", "--", "ls"}) {
+		t.Errorf("unexpected run: %#v", op.Results["run"])
+	}
+	if op.Results["verbose"] != true || !reflect.DeepEqual(op.Args, []string{"file"}) {
+		t.Errorf("unexpected verbose %v and args %q", op.Results["verbose"], op.Args)
+	}
+	if occ := op.Occurrences[0]; occ.Raw != "grep -v -- '{}'" {
+		t.Errorf("unexpected raw: %q", occ.Raw)
+	}
+
+	// the capture without a terminator is emitted last
+	expected := []string{"--exec", "grep", "-v", "--", "{}", ";", "--verbose", "--run", "ssh", "-v", "host", "--", "ls"}
+	if got := op.ToArgs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected ToArgs: %q", got)
+	}
+
+	if err := op.ProcessAll([]string{"--exec=rm", ";", "-x"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["exec"], []string{"rm"}) || !reflect.DeepEqual(op.Results["run"], []string{}) {
+		t.Errorf("unexpected exec %#v and run %#v", op.Results["exec"], op.Results["run"])
+	}
+
+	// a `--` before the option still ends option processing
+	if err := op.ProcessAll([]string{"--", "--exec", "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Args, []string{"--exec", "ls"}) {
+		t.Errorf("unexpected args: %q", op.Args)
+	}
+
+	if err := op.ProcessAll([]string{"--exec", "ls", "-v"}); err == nil {
+		t.Errorf("expected missing terminator error")
+	}
+
+	var cmd []string
+	op = NewDirectAssignParser(map[string]interface{}{"cmd=s...": &cmd})
+	if err := op.ProcessAll([]string{"--cmd", "echo", "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmd, []string{"echo", "hi"}) {
+		t.Errorf("unexpected cmd: %#v", cmd)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic for integer capture")
+			}
+		}()
+		NewParser([]string{"n=i..."})
+	}()
+}
+
+func TestCounterValues(t *testing.T) {
+	op := NewParser([]string{"v|verbose|-q|-quiet+{-1,3}", "d|debug+"})
+
+	for _, tt := range []struct {
+		args     []string
+		expected int64
+	}{
+		{[]string{"-v", "-v"}, 2},
+		{[]string{"-v", "-q", "--quiet"}, -1},
+		{[]string{"-q", "-q"}, -1},
+		{[]string{"-v", "-v", "-v", "-v"}, 3},
+		{[]string{"--verbose=2", "-v"}, 3},
+		{[]string{"-v", "-v3", "-q"}, 2},
+		{[]string{"--verbose=10"}, 3},
+	} {
+		op.Reset()
+		if err := op.ProcessAll(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if op.Results["verbose"] != tt.expected {
+			t.Errorf("expected %d for %q, got %v", tt.expected, tt.args, op.Results["verbose"])
+		}
+	}
+
+	if err := op.ProcessAll([]string{"--quiet=2"}); err == nil {
+		t.Errorf("expected error for decrement alias with a value")
+	}
+
+	op.Reset()
+	if err := op.ProcessAll([]string{"-q", "--debug=2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := op.ToArgs(); !reflect.DeepEqual(got, []string{"--debug=2", "--verbose=-1"}) {
+		t.Errorf("unexpected ToArgs: %q", got)
+	}
+
+	// counters are written as a single value so limits do not change
+	// the value when the arguments are parsed again
+	clamped := NewParser([]string{"v|verbose+{2,5}"})
+	if err := clamped.ProcessAll([]string{"--verbose=3"}); err != nil {
+		t.Fatal(err)
+	}
+	args := clamped.ToArgs()
+	if !reflect.DeepEqual(args, []string{"--verbose=3"}) {
+		t.Errorf("unexpected ToArgs: %q", args)
+	}
+	clamped.Reset()
+	if err := clamped.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if clamped.Results["verbose"] != int64(3) {
+		t.Errorf("unexpected verbose after round trip: %v", clamped.Results["verbose"])
+	}
+
+	// short canonical aliases take the count attached
+	short := NewParser([]string{"verbose|v|-q+"})
+	for _, tt := range []struct {
+		args     []string
+		expected string
+		count    int64
+	}{
+		{[]string{"-v", "-v"}, "-v2", 2},
+		{[]string{"-q"}, "-v-1", -1},
+	} {
+		short.Reset()
+		if err := short.ProcessAll(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		args := short.ToArgs()
+		if !reflect.DeepEqual(args, []string{tt.expected}) {
+			t.Errorf("unexpected ToArgs for %q: %q", tt.args, args)
+		}
+		short.Reset()
+		if err := short.ProcessAll(args); err != nil {
+			t.Fatal(err)
+		}
+		if short.Results["v"] != tt.count {
+			t.Errorf("unexpected count after round trip of %q: %v", args, short.Results["v"])
+		}
+	}
+	info := op.Options()[1]
+	if !reflect.DeepEqual(info.Aliases, []string{"-v", "--verbose"}) || !reflect.DeepEqual(info.DecrementAliases, []string{"-q", "--quiet"}) {
+		t.Errorf("unexpected aliases %q and %q", info.Aliases, info.DecrementAliases)
+	}
+
+	// counters keep the type of direct assignment destinations
+	var level int
+	op = NewDirectAssignParser(map[string]interface{}{"l|level|-s+{0,}": &level})
+	if err := op.ProcessAll([]string{"-l", "-l", "-s", "-s", "-s", "-l"}); err != nil {
+		t.Fatal(err)
+	}
+	if level != 1 {
+		t.Errorf("unexpected level: %d", level)
+	}
+
+	for _, spec := range []string{"v+{3,1}", "v+{1}", "v+{x,}", "-q+", "v|-q=s"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic for spec %s", spec)
+				}
+			}()
+			NewParser([]string{spec})
+		}()
+	}
+}
+
+func TestRepeatPolicy(t *testing.T) {
+	op := NewParser([]string{"u|user=s", "n|name=s", "t|tags=s@", "f|force"})
+
+	args := []string{"-u", "a", "--user=b", "-t", "x", "-t", "y"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["user"] != "b" {
+		t.Errorf("expected last value to win, got %v", op.Results["user"])
+	}
+
+	op.Repeat = RepeatFirst
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["user"] != "a" {
+		t.Errorf("expected first value to win, got %v", op.Results["user"])
+	}
+	if len(op.Occurrences) != 4 {
+		t.Errorf("expected every occurrence to be recorded, got %d", len(op.Occurrences))
+	}
+
+	// lists still collect every value
+	op.Reset()
+	op.Repeat = RepeatError
+	if err := op.ProcessAll([]string{"-t", "x", "-t", "y", "-u", "a", "-f"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["tags"], []string{"x", "y"}) {
+		t.Errorf("unexpected tags: %#v", op.Results["tags"])
+	}
+
+	err := op.ProcessAll([]string{"-f", "-u", "a", "x", "--user=b"})
+	expected := "option --user given more than once, as -u at argument 2 and --user at argument 5"
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := op.ProcessAll([]string{"-f", "--force"}); err == nil {
+		t.Errorf("expected error for repeated flag")
+	}
+
+	// the option policy overrides the parser policy
+	op.Describe("name", OptionMeta{Repeat: RepeatLast})
+	if err := op.ProcessAll([]string{"-n", "a", "-n", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["name"] != "b" {
+		t.Errorf("expected last name to win, got %v", op.Results["name"])
+	}
+}
+
+func TestNegate(t *testing.T) {
+	op := NewParser([]string{"I|include=s@!", "D|define=s%!", "color!", "v|verbose"})
+	op.Results = map[string]interface{}{
+		"include": []string{"/usr/include"},
+		"define":  map[string]string{"DEBUG": "1"},
+		"color":   true,
+	}
+
+	args := []string{"-I", "a", "--no-include", "-I", "b", "--no-define", "--no-color"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(op.Results["include"], []string{"b"}) {
+		t.Errorf("unexpected include: %#v", op.Results["include"])
+	}
+	if !reflect.DeepEqual(op.Results["define"], map[string]string{}) {
+		t.Errorf("unexpected define: %#v", op.Results["define"])
+	}
+	if op.Results["color"] != false {
+		t.Errorf("unexpected color: %v", op.Results["color"])
+	}
+	if occ := op.Occurrences[1]; occ.Name != "include" || occ.Value != nil {
+		t.Errorf("unexpected occurrence: %#v", occ)
+	}
+	args = op.ToArgs()
+	if !reflect.DeepEqual(args, []string{"--no-color", "--no-define", "--no-include", "--include", "b"}) {
+		t.Errorf("unexpected ToArgs: %q", args)
+	}
+
+	// the arguments reproduce the results on a parser with the same
+	// defaults
+	reparsed := NewParser([]string{"I|include=s@!", "D|define=s%!", "color!", "v|verbose"})
+	reparsed.Results = map[string]interface{}{
+		"include": []string{"/usr/include"},
+		"define":  map[string]string{"DEBUG": "1"},
+		"color":   true,
+	}
+	if err := reparsed.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reparsed.Results, op.Results) {
+		t.Errorf("unexpected results after round trip:\n%#v\n%#v", reparsed.Results, op.Results)
+	}
+
+	// Reset restores the defaults that were cleared
+	op.Reset()
+	if !reflect.DeepEqual(op.Results["include"], []string{"/usr/include"}) {
+		t.Errorf("unexpected include after reset: %#v", op.Results["include"])
+	}
+
+	for _, args := range [][]string{{"--no-include=a"}, {"--no-verbose"}} {
+		if err := op.ProcessAll(args); err == nil {
+			t.Errorf("expected error for %q", args)
+		}
+	}
+
+	tags := []string{"default"}
+	op = NewDirectAssignParser(map[string]interface{}{"t|tags=s@!": &tags})
+	if err := op.ProcessAll([]string{"--no-tags", "-t", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"x"}) {
+		t.Errorf("unexpected tags: %#v", tags)
+	}
+
+	for _, spec := range []string{"n=s!", "v+!", "x=s@!"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic for spec %s", spec)
+				}
+			}()
+			NewParser([]string{spec})
+		}()
+	}
+}
+
+func TestMapDestConversion(t *testing.T) {
+	// integer keys are not converted to strings as runes
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic for integer keys in a string keyed map")
+			}
+		}()
+		NewDirectAssignParser(map[string]interface{}{"w=f{i}": &map[string]float64{}})
+	}()
+
+	weights := map[int8]float32{}
+	op := NewDirectAssignParser(map[string]interface{}{"w=f{i}": &weights})
+	if err := op.ProcessAll([]string{"-w", "100=0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(weights, map[int8]float32{100: 0.5}) {
+		t.Errorf("unexpected weights: %#v", weights)
+	}
+	// keys that overflow the map key type are rejected, not wrapped
+	if err := op.ProcessAll([]string{"-w", "300=1"}); err == nil {
+		t.Errorf("expected overflow error")
+	}
+	if _, ok := weights[44]; ok || len(weights) != 1 {
+		t.Errorf("unexpected weights after overflow: %#v", weights)
+	}
+
+	lists := map[uint8][]int8{}
+	op = NewDirectAssignParser(map[string]interface{}{"l=i{i}@": &lists})
+	for _, args := range [][]string{{"-l", "-1=1"}, {"-l", "1=128"}} {
+		if err := op.ProcessAll(args); err == nil {
+			t.Errorf("expected overflow error for %q", args)
+		}
+	}
+}
+
+func TestProcessAllTwice(t *testing.T) {
+	op := NewParser([]string{"u|user=s", "v|verbose"})
+	op.Describe("user", OptionMeta{Required: true, Env: "OPTIGO_TEST_TWICE_USER"})
+
+	if err := op.ProcessAll([]string{"-u", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	// options seen by an earlier call do not satisfy Required
+	if err := op.ProcessAll([]string{"-v"}); err == nil {
+		t.Errorf("expected missing required option error")
+	}
+
+	// or stop the environment from being used
+	t.Setenv("OPTIGO_TEST_TWICE_USER", "alice")
+	if err := op.ProcessAll([]string{"-v"}); err != nil {
+		t.Fatal(err)
+	}
+	if op.Results["user"] != "alice" {
+		t.Errorf("unexpected user: %v", op.Results["user"])
+	}
+}
+
+func TestFileOptionsOpenedAfterParsing(t *testing.T) {
+	dir := t.TempDir()
+	precious := filepath.Join(dir, "precious")
+	if err := os.WriteFile(precious, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	op := NewParser([]string{"o|output=w", "i|input=r@"})
+	if err := op.ProcessAll([]string{"-o", precious, "--bogus"}); err == nil {
+		t.Fatal("expected unknown option error")
+	}
+	if data, _ := os.ReadFile(precious); string(data) != "data" {
+		t.Errorf("file truncated by a failed parse: %q", data)
+	}
+
+	// only the last of a repeated single file option is opened
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := op.ProcessAll([]string{"-o", a, "-o", b, "-i", precious, "-i", precious}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(a); !os.IsNotExist(err) {
+		t.Errorf("expected %s to not be created", a)
+	}
+	out := op.Results["output"].(*os.File)
+	if out.Name() != b {
+		t.Errorf("unexpected output: %s", out.Name())
+	}
+	if op.Occurrences[0].Value != a || op.Occurrences[1].Value != out {
+		t.Errorf("unexpected occurrence values: %#v", op.Occurrences[:2])
+	}
+	inputs := op.Results["input"].([]*os.File)
+	if len(inputs) != 2 {
+		t.Fatalf("unexpected inputs: %v", inputs)
+	}
+
+	// Reset closes the files opened by the parser
+	op.Reset()
+	for _, f := range append(inputs, out) {
+		if err := f.Close(); err == nil {
+			t.Errorf("expected %s to be closed by Reset", f.Name())
+		}
+	}
+
+	// a second call closes the file it replaces
+	if err := op.ProcessAll([]string{"-o", a}); err != nil {
+		t.Fatal(err)
+	}
+	first := op.Results["output"].(*os.File)
+	if err := op.ProcessAll([]string{"-o", b}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); err == nil {
+		t.Errorf("expected replaced file to be closed")
+	}
+	op.Reset()
+}
+
+func TestFileOptionsReadBeforeWrite(t *testing.T) {
+	dir := t.TempDir()
+	precious := filepath.Join(dir, "precious")
+	if err := os.WriteFile(precious, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out *os.File
+	var in []*os.File
+	op := NewDirectAssignParser(map[string]interface{}{
+		"o|output=w": &out,
+		"i|input=r@": &in,
+	})
+	missing := filepath.Join(dir, "missing")
+	if err := op.ProcessAll([]string{"-o", precious, "-i", precious, "-i", missing}); err == nil {
+		t.Fatal("expected error for missing input")
+	}
+	if data, _ := os.ReadFile(precious); string(data) != "data" {
+		t.Errorf("file truncated before a missing input was found: %q", data)
+	}
+	if out != nil || in != nil || len(op.files) != 0 {
+		t.Errorf("files left open after error: %v %v %v", out, in, op.files)
+	}
+}
+
+func TestCallbackValueType(t *testing.T) {
+	for spec, cb := range map[string]interface{}{
+		"n=i":     func(v int) {},
+		"s=s@":    func(v []string) {},
+		"f=f{2}":  func(v float64) {},
+		"m=s%":    func(v string) {},
+		"b":       func(v string) {},
+		"x=s...;": func(name string, v string) error { return nil },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic for %s with %T", spec, cb)
+				}
+			}()
+			NewDirectAssignParser(map[string]interface{}{spec: cb})
+		}()
+	}
+
+	var got []interface{}
+	op := NewDirectAssignParser(map[string]interface{}{
+		"n=i":     func(v int64) { got = append(got, v) },
+		"f=f{2}":  func(v []float64) { got = append(got, v) },
+		"m=s%":    func(name string, v interface{}) { got = append(got, name) },
+		"x=s...;": func(v []string) { got = append(got, v) },
+		"v+":      func(v bool) { got = append(got, v) },
+	})
+	if err := op.ProcessAll([]string{"-n", "1", "-f", "1", "2", "-m", "a=b", "-x", "y", ";", "-v"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{int64(1), []float64{1, 2}, "m", []string{"y"}, true}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected callback values: %#v", got)
+	}
+
+	// counter callbacks are called per increment and take no value
+	if err := op.ProcessAll([]string{"-v3"}); err == nil {
+		t.Errorf("expected error for a counter callback given a value")
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// OptionMeta holds optional metadata for an option.  It is attached
+// to an option with OptionParser.Describe.
+type OptionMeta struct {
+	// Usage is a short description of the option for help output.
+	Usage string
+
+	// Default documents the value used when the option is not given.
+	// When unset, the initial value of a direct assignment destination
+	// is reported instead.
+	Default interface{}
+
+	// Required options must be given or ProcessAll returns an error.
+	Required bool
+
+	// Env names an environment variable used for the option value when
+	// the option is not given in the arguments.  Flags are set when the
+	// variable holds a true value as understood by strconv.ParseBool,
+	// and counters are set to the integer value of the variable, which
+	// must not be negative.
+	Env string
+
+	// Repeat overrides OptionParser.Repeat for this option.
+	Repeat RepeatPolicy
+}
+
+// OptionInfo is a read-only description of a registered option as
+// returned by OptionParser.Options.
+type OptionInfo struct {
+	OptionMeta
+
+	// Name is the canonical name of the option, the last alias in the
+	// spec, used as the key in OptionParser.Results.
+	Name string
+
+	// Aliases are all of the option aliases in spec order, including
+	// their leading dashes, except for DecrementAliases.
+	Aliases []string
+
+	// DecrementAliases are the aliases that lower a counter, like the
+	// -q in "v|verbose|-q+".
+	DecrementAliases []string
+
+	// NegatedAliases are the --no-NAME aliases of options with a spec
+	// ending in "!", which set flags to false and clear lists and maps.
+	NegatedAliases []string
+
+	// Type is the value type: "string", "int", "float", "bool",
+	// "infile" or "outfile".
+	Type string
+
+	// KeyType is the type of the keys for map options: "string", "int"
+	// or "float".  It is empty for other options.
+	KeyType string
+
+	// ListSeparator is the delimiter each value of a split append
+	// option like "tags=s@," is broken on.  It is empty for other
+	// options.
+	ListSeparator string
+
+	// MinValues and MaxValues are the number of values consumed by
+	// each occurrence of options with a repeat count like "rgb=i{3}".
+	// MaxValues is -1 when there is no upper bound.  Both are zero for
+	// other options.
+	MinValues int
+	MaxValues int
+
+	// Terminator is the argument that ends the values captured by an
+	// option like "exec...;".  It is empty for other options, and for
+	// capture options that take the rest of the arguments.
+	Terminator string
+
+	// Action is how values are stored: "increment", "append", "map",
+	// "maplist", "nested", "capture" or "assign".
+	Action string
+
+	// TakesValue is true if the option requires an argument.
+	TakesValue bool
+
+	// Dest is the kind of destination the option was registered with:
+	// reflect.Invalid when stored in OptionParser.Results, reflect.Ptr
+	// for direct assignment and reflect.Func for callbacks.
+	Dest reflect.Kind
+}
+
+// Options returns a description of every option registered with the
+// parser, sorted by canonical name.
+func (o *OptionParser) Options() []OptionInfo {
+	seen := make(map[string]bool)
+	infos := make([]OptionInfo, 0)
+	for _, opt := range o.actions {
+		if seen[opt.name] {
+			continue
+		}
+		seen[opt.name] = true
+		infos = append(infos, opt.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Describe attaches metadata to the option `name`, which may be any
+// alias of the option with or without leading dashes.
+func (o *OptionParser) Describe(name string, meta OptionMeta) error {
+	if o.shared {
+		o.actions = o.actions.copy()
+		o.shared = false
+	}
+	opt, ok := o.findOption(name)
+	if !ok {
+		return fmt.Errorf("unknown option: %s", name)
+	}
+	*opt.meta = meta
+	return nil
+}
+
+// copy returns a copy of the actions with unshared metadata.
+func (a actions) copy() actions {
+	metas := make(map[*OptionMeta]*OptionMeta)
+	c := make(actions)
+	for dashName, opt := range a {
+		if _, ok := metas[opt.meta]; !ok {
+			meta := *opt.meta
+			metas[opt.meta] = &meta
+		}
+		opt.meta = metas[opt.meta]
+		c[dashName] = opt
+	}
+	return c
+}
+
+func (opt *option) info() OptionInfo {
+	info := OptionInfo{
+		Name:       opt.name,
+		Aliases:    append([]string(nil), opt.aliases...),
+		Type:       opt.dataType.String(),
+		Action:     opt.action.String(),
+		TakesValue: !opt.unary,
+		Dest:       opt.dest.Kind(),
+	}
+	if opt.isMap() {
+		info.KeyType = opt.keyType.String()
+	}
+	if opt.listSep != 0 {
+		info.ListSeparator = string(opt.listSep)
+	}
+	info.MinValues, info.MaxValues = opt.minVals, opt.maxVals
+	info.Terminator = opt.term
+	if len(opt.decr) > 0 {
+		info.DecrementAliases = append([]string(nil), opt.decr...)
+	}
+	if len(opt.neg) > 0 {
+		info.NegatedAliases = append([]string(nil), opt.neg...)
+	}
+	if opt.meta != nil {
+		info.OptionMeta = *opt.meta
+	}
+	if info.Default == nil && opt.dflt.IsValid() {
+		info.Default = copyValue(opt.dflt).Interface()
+	}
+	return info
+}
+
+// copyValue returns a copy of val where slices and maps do not share
+// storage with the original.
+func copyValue(val reflect.Value) reflect.Value {
+	switch val.Kind() {
+	case reflect.Slice:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		c := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+		reflect.Copy(c, val)
+		return c
+	case reflect.Map:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		c := reflect.MakeMapWithSize(val.Type(), val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return c
+	case reflect.Interface:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		c := reflect.New(val.Type()).Elem()
+		c.Set(copyValue(val.Elem()))
+		return c
+	}
+	c := reflect.New(val.Type()).Elem()
+	c.Set(val)
+	return c
+}
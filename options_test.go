@@ -0,0 +1,51 @@
+package optigo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	count := int64(1)
+	names := []string{"a"}
+	op := NewDirectAssignParser(map[string]interface{}{
+		"v|verbose+":  &count,
+		"n|name=s@":   &names,
+		"h|help":      func() {},
+		"o|opt|x=s{}": func(name string, value interface{}) {},
+	})
+	if err := op.Describe("verbose", OptionMeta{Usage: "be chatty"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Describe("-n", OptionMeta{Required: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Describe("bogus", OptionMeta{}); err == nil {
+		t.Fail()
+	}
+
+	// changing the destination does not change the reported default
+	names = append(names, "b")
+
+	expected := []OptionInfo{
+		{Name: "help", Aliases: []string{"-h", "--help"}, Type: "bool", Action: "assign", Dest: reflect.Func},
+		{OptionMeta: OptionMeta{Required: true, Default: []string{"a"}}, Name: "name", Aliases: []string{"-n", "--name"}, Type: "string", Action: "append", TakesValue: true, Dest: reflect.Ptr},
+		{OptionMeta: OptionMeta{Usage: "be chatty", Default: int64(1)}, Name: "verbose", Aliases: []string{"-v", "--verbose"}, Type: "int", Action: "increment", Dest: reflect.Ptr},
+		{Name: "x", Aliases: []string{"-o", "--opt", "-x"}, Type: "string", KeyType: "string", Action: "map", TakesValue: true, Dest: reflect.Func},
+	}
+	if options := op.Options(); !reflect.DeepEqual(options, expected) {
+		t.Errorf("unexpected options:\n%#v\n%#v", options, expected)
+	}
+}
+
+func TestRequiredOption(t *testing.T) {
+	op := NewParser([]string{"u|user=s", "v|verbose+"})
+	op.Describe("user", OptionMeta{Required: true})
+
+	if err := op.ProcessAll([]string{"-v"}); err == nil || err.Error() != "missing required option: --user" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := op.ProcessAll([]string{"-u", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import "fmt"
+
+// RepeatPolicy controls what happens when an option holding a single
+// value, like "user=s" or a flag, is given more than once.  Options
+// that collect values, like counters, lists and maps, are not affected.
+type RepeatPolicy int
+
+const (
+	// RepeatDefault uses OptionParser.Repeat for options, and
+	// RepeatLast for the parser.
+	RepeatDefault RepeatPolicy = iota
+
+	// RepeatLast keeps the value of the last occurrence.
+	RepeatLast
+
+	// RepeatFirst keeps the value of the first occurrence and ignores
+	// the rest.
+	RepeatFirst
+
+	// RepeatError rejects repeated options with an error.
+	RepeatError
+)
+
+func (p RepeatPolicy) String() string {
+	switch p {
+	case RepeatDefault:
+		return "default"
+	case RepeatLast:
+		return "last"
+	case RepeatFirst:
+		return "first"
+	case RepeatError:
+		return "error"
+	}
+	return fmt.Sprintf("RepeatPolicy(%d)", int(p))
+}
+
+// repeatPolicy returns the policy for opt, falling back to the parser
+// wide policy.
+func (o *OptionParser) repeatPolicy(opt option) RepeatPolicy {
+	if opt.action != atASSIGN && opt.action != atCAPTURE {
+		return RepeatLast
+	}
+	if opt.meta != nil && opt.meta.Repeat != RepeatDefault {
+		return opt.meta.Repeat
+	}
+	if o.Repeat != RepeatDefault {
+		return o.Repeat
+	}
+	return RepeatLast
+}
+
+// checkRepeat applies the repeat policy for opt to occ, reporting
+// whether the value of occ should be used.
+func (o *OptionParser) checkRepeat(opt option, occ Occurrence) (bool, error) {
+	i, repeated := o.first[opt.name]
+	if !repeated && occ.Index >= 0 {
+		o.first[opt.name] = len(o.Occurrences)
+	}
+	policy := o.repeatPolicy(opt)
+	if policy == RepeatLast || !repeated {
+		return true, nil
+	}
+	if policy == RepeatFirst {
+		return false, nil
+	}
+	prev := o.Occurrences[i]
+	return false, fmt.Errorf("option --%s given more than once, as %s at argument %d and %s at argument %d",
+		opt.name, prev.Alias, prev.Index+1, occ.Alias, occ.Index+1)
+}
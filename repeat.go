@@ -0,0 +1,132 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// repeatSuffix checks if spec ends with a repeat count like "{2}",
+// "{1,3}" or "{2,}", returning the spec without the count along with
+// the minimum and maximum number of values.  The maximum is -1 when
+// there is no upper bound.
+func repeatSuffix(spec string) (string, int, int, bool) {
+	open := strings.LastIndexByte(spec, '{')
+	if open == -1 || !strings.HasSuffix(spec, "}") {
+		return spec, 0, 0, false
+	}
+	lo, hi, ranged := strings.Cut(spec[open+1:len(spec)-1], ",")
+	min, err := strconv.Atoi(lo)
+	if err != nil || lo[0] == '+' || lo[0] == '-' {
+		return spec, 0, 0, false
+	}
+	max := min
+	if ranged {
+		if hi == "" {
+			max = -1
+		} else if max, err = strconv.Atoi(hi); err != nil || hi[0] == '+' || hi[0] == '-' {
+			return spec, 0, 0, false
+		}
+	}
+	return spec[:open], min, max, true
+}
+
+// takesValues reports whether each occurrence of the option consumes
+// a repeat count of values.
+func (o *option) takesValues() bool {
+	return o.minVals > 0
+}
+
+// collectValues appends the values for an occurrence of opt from the
+// front of args to vals, returning them along with the number of args
+// used.  Values are always taken until the minimum is reached, beyond
+// that values are taken up to the maximum until an option, `--` or a
+// value that does not parse is found.
+func (o *OptionParser) collectValues(opt option, vals, args []string) ([]string, int) {
+	n := 0
+	for n < len(args) && (opt.maxVals == -1 || len(vals) < opt.maxVals) {
+		if len(vals) >= opt.minVals && !o.isOptionalValue(opt, args[n]) {
+			break
+		}
+		vals = append(vals, args[n])
+		n++
+	}
+	return vals, n
+}
+
+func (o *OptionParser) isOptionalValue(opt option, arg string) bool {
+	if arg == "--" {
+		return false
+	}
+	numeric := opt.dataType == dtINTEGER || opt.dataType == dtFLOAT
+	if o.isOption(arg) {
+		if _, ok := o.actions[arg]; ok || !numeric || !isNumber(arg) {
+			return false
+		}
+	}
+	if numeric {
+		if _, err := opt.parseValue(arg); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// parseValues parses the values of a single occurrence of opt into a
+// slice of the option type.
+func (o *OptionParser) parseValues(opt option, vals []string) (interface{}, error) {
+	if len(vals) < opt.minVals || (opt.maxVals != -1 && len(vals) > opt.maxVals) {
+		return nil, fmt.Errorf("option --%s requires %s, got %d", opt.name, opt.valueCount(), len(vals))
+	}
+	elem := opt.dataType.goType()
+	if opt.isFile() {
+		elem = reflect.TypeOf(filePath(""))
+	}
+	values := reflect.MakeSlice(reflect.SliceOf(elem), len(vals), len(vals))
+	for i, val := range vals {
+		parsed, err := opt.parseValue(val)
+		if err != nil {
+			return nil, err
+		}
+		values.Index(i).Set(reflect.ValueOf(parsed))
+	}
+	return values.Interface(), nil
+}
+
+// valueCount describes how many values the option requires, such as
+// "2 values" or "1 to 3 values".
+func (o *option) valueCount() string {
+	switch {
+	case o.maxVals == -1:
+		return fmt.Sprintf("at least %d value%s", o.minVals, plural(o.minVals))
+	case o.maxVals == o.minVals:
+		return fmt.Sprintf("%d value%s", o.minVals, plural(o.minVals))
+	}
+	return fmt.Sprintf("%d to %d values", o.minVals, o.maxVals)
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,149 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Command is a named command with its own OptionParser, dispatched
+// by a REPL.
+type Command struct {
+	// Summary is a one line description of the command for help output.
+	Summary string
+
+	// Parser processes the arguments following the command name.  It is
+	// Reset before every use.  A nil Parser accepts only positional
+	// arguments.
+	Parser *OptionParser
+
+	// Run is called once the arguments have been processed.  Positional
+	// arguments are available in op.Args.
+	Run func(op *OptionParser, out io.Writer) error
+}
+
+// REPL reads commands line by line, splits them with SplitArgs and
+// dispatches them to Commands by their first argument.  The builtin
+// `help [command]` and `exit` commands are always available.
+type REPL struct {
+	// Prompt is written to the output before each line is read.
+	Prompt string
+
+	// Commands maps command names to their Command.
+	Commands map[string]*Command
+}
+
+// Run processes lines from in until it is exhausted or the `exit`
+// command is read.  Output, including parse and command errors, is
+// written to out.  An error is only returned if in or out fail.
+func (r *REPL) Run(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		if r.Prompt != "" {
+			if _, err := io.WriteString(out, r.Prompt); err != nil {
+				return err
+			}
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		args, err := SplitArgs(scanner.Text())
+		if err != nil {
+			if _, err := fmt.Fprintln(out, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "exit":
+			return nil
+		case "help":
+			err = r.help(out, args[1:])
+		default:
+			err = r.dispatch(out, args)
+		}
+		if err != nil {
+			if _, err := fmt.Fprintf(out, "%s: %s\n", args[0], err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *REPL) dispatch(out io.Writer, args []string) error {
+	cmd, ok := r.Commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command, try help")
+	}
+	op := cmd.Parser
+	if op == nil {
+		empty := NewParser(nil)
+		op = &empty
+	}
+	op.Reset()
+	if err := op.ProcessAll(args[1:]); err != nil {
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		return err
+	}
+	if cmd.Run == nil {
+		return nil
+	}
+	return cmd.Run(op, out)
+}
+
+func (r *REPL) help(out io.Writer, args []string) error {
+	if len(args) > 0 {
+		cmd, ok := r.Commands[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown command: %s", args[0])
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s\n", args[0], cmd.Summary); err != nil {
+			return err
+		}
+		if cmd.Parser == nil {
+			return nil
+		}
+		return cmd.Parser.WriteHelp(out)
+	}
+
+	names := make([]string, 0, len(r.Commands))
+	for name := range r.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, r.Commands[name].Summary)
+	}
+	fmt.Fprintf(tw, "  %s\t%s\n", "help [command]", "show help")
+	fmt.Fprintf(tw, "  %s\t%s\n", "exit", "exit")
+	return tw.Flush()
+}
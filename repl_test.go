@@ -0,0 +1,49 @@
+package optigo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	repl := REPL{
+		Commands: map[string]*Command{
+			"echo": {
+				Summary: "echo arguments",
+				Run: func(op *OptionParser, out io.Writer) error {
+					fmt.Fprintln(out, strings.Join(op.Args, " "))
+					return nil
+				},
+			},
+			"fail": {
+				Summary: "always fails",
+				Run: func(op *OptionParser, out io.Writer) error {
+					return fmt.Errorf("failed")
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	input := "\necho a 'b c'\nbogus\necho 'oops\nfail\nhelp\nhelp bogus\n"
+	if err := repl.Run(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `a b c
+bogus: unknown command, try help
+line 1: unterminated single quote
+fail: failed
+  echo            echo arguments
+  fail            always fails
+  help [command]  show help
+  exit            exit
+help: unknown command: bogus
+`
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
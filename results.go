@@ -0,0 +1,163 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Lookup returns the value of the option `name` converted to T.  The
+// name may be any alias of the option, with or without leading dashes.
+// For parsers created with NewDirectAssignParser the current value of
+// the destination is returned.  Values can be converted between
+// compatible numeric widths (such as int64 to int or []float64 to
+// []float32); an error is returned when the option is unknown, has not
+// been set, or holds a value that cannot be converted to T.
+func Lookup[T any](op *OptionParser, name string) (T, error) {
+	var zero T
+	opt, ok := op.findOption(name)
+	if !ok {
+		return zero, fmt.Errorf("unknown option: %s", name)
+	}
+
+	var val reflect.Value
+	if opt.dest.IsValid() {
+		if opt.dest.Kind() != reflect.Ptr {
+			return zero, fmt.Errorf("option --%s does not store a value", opt.name)
+		}
+		val = opt.dest.Elem()
+	} else if v, ok := op.Results[opt.name]; ok {
+		val = reflect.ValueOf(v)
+	} else {
+		return zero, fmt.Errorf("option --%s is not set", opt.name)
+	}
+
+	target := reflect.TypeOf((*T)(nil)).Elem()
+	converted, ok := convertValue(val, target)
+	if !ok {
+		return zero, fmt.Errorf("option --%s is a %s %s option holding %s, which cannot be converted to %s", opt.name, opt.dataType, opt.action, val.Type(), target)
+	}
+	return converted.Interface().(T), nil
+}
+
+// Get returns the value of the option `name` converted to T, see
+// Lookup for details.  The boolean result is false if the value is
+// not set or cannot be converted to T.
+func Get[T any](op *OptionParser, name string) (T, bool) {
+	val, err := Lookup[T](op, name)
+	return val, err == nil
+}
+
+// MustGet returns the value of the option `name` converted to T, see
+// Lookup for details.  It panics if the value is not set or cannot be
+// converted to T.
+func MustGet[T any](op *OptionParser, name string) T {
+	val, err := Lookup[T](op, name)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+func (o *OptionParser) findOption(name string) (option, bool) {
+	for _, dashName := range []string{name, "--" + name, "-" + name} {
+		if opt, ok := o.actions[dashName]; ok {
+			return opt, true
+		}
+	}
+	return option{}, false
+}
+
+func isSigned(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// convertValue converts val to type t, allowing numeric values to
+// change width so long as they do not overflow, and converting slices
+// and maps element by element.
+func convertValue(val reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	out := reflect.New(t).Elem()
+	if val.Type().AssignableTo(t) {
+		out.Set(val)
+		return out, true
+	}
+
+	sk, tk := val.Kind(), t.Kind()
+	switch {
+	case isSigned(sk) && isSigned(tk):
+		if out.OverflowInt(val.Int()) {
+			return out, false
+		}
+	case isSigned(sk) && isUnsigned(tk):
+		if val.Int() < 0 || out.OverflowUint(uint64(val.Int())) {
+			return out, false
+		}
+	case isUnsigned(sk) && isSigned(tk):
+		if val.Uint() > math.MaxInt64 || out.OverflowInt(int64(val.Uint())) {
+			return out, false
+		}
+	case isUnsigned(sk) && isUnsigned(tk):
+		if out.OverflowUint(val.Uint()) {
+			return out, false
+		}
+	case isFloat(sk) && isFloat(tk):
+		if out.OverflowFloat(val.Float()) {
+			return out, false
+		}
+	case sk == reflect.Slice && tk == reflect.Slice:
+		out = reflect.MakeSlice(t, val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			elem, ok := convertValue(val.Index(i), t.Elem())
+			if !ok {
+				return out, false
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, true
+	case sk == reflect.Map && tk == reflect.Map:
+		out = reflect.MakeMapWithSize(t, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			k, ok := convertValue(iter.Key(), t.Key())
+			if !ok {
+				return out, false
+			}
+			v, ok := convertValue(iter.Value(), t.Elem())
+			if !ok {
+				return out, false
+			}
+			out.SetMapIndex(k, v)
+		}
+		return out, true
+	default:
+		return out, false
+	}
+	out.Set(val.Convert(t))
+	return out, true
+}
@@ -0,0 +1,80 @@
+package optigo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	op := NewParser([]string{
+		"i|int=i",
+		"f|float=f",
+		"I|ints=i@",
+		"m|map=f%",
+		"s|str=s",
+		"unset=s",
+	})
+	args := []string{"-i", "300", "-f", "1.5", "-I", "1", "-I", "2", "--map", "a=2.5", "--str", "x"}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := Get[int](&op, "int"); !ok || v != 300 {
+		t.Errorf("unexpected int: %v %v", v, ok)
+	}
+	if v, ok := Get[int64](&op, "-i"); !ok || v != 300 {
+		t.Errorf("unexpected int: %v %v", v, ok)
+	}
+	if _, ok := Get[int8](&op, "int"); ok {
+		t.Errorf("expected overflow converting 300 to int8")
+	}
+	if v, ok := Get[float32](&op, "f"); !ok || v != 1.5 {
+		t.Errorf("unexpected float: %v %v", v, ok)
+	}
+	if v, ok := Get[[]int](&op, "ints"); !ok || !reflect.DeepEqual(v, []int{1, 2}) {
+		t.Errorf("unexpected ints: %v %v", v, ok)
+	}
+	if v, ok := Get[map[string]float32](&op, "map"); !ok || !reflect.DeepEqual(v, map[string]float32{"a": 2.5}) {
+		t.Errorf("unexpected map: %v %v", v, ok)
+	}
+	if v := MustGet[string](&op, "str"); v != "x" {
+		t.Errorf("unexpected str: %v", v)
+	}
+	if v, ok := Get[interface{}](&op, "str"); !ok || v != "x" {
+		t.Errorf("unexpected str: %v %v", v, ok)
+	}
+
+	if _, err := Lookup[string](&op, "unset"); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Lookup[string](&op, "bogus"); err == nil || !strings.Contains(err.Error(), "unknown option") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Lookup[[]string](&op, "ints"); err == nil || !strings.Contains(err.Error(), "int append option") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetDirectAssign(t *testing.T) {
+	var count int64
+	op := NewDirectAssignParser(map[string]interface{}{
+		"v|verbose+": &count,
+	})
+	if err := op.ProcessAll([]string{"-v", "-v"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := Get[uint](&op, "verbose"); !ok || v != 2 {
+		t.Errorf("unexpected verbose: %v %v", v, ok)
+	}
+}
+
+func TestMustGetPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fail()
+		}
+	}()
+	op := NewParser([]string{"s|str=s"})
+	MustGet[int](&op, "str")
+}
@@ -0,0 +1,172 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"strings"
+)
+
+type word struct {
+	text string
+	line int
+}
+
+type wordsError struct {
+	line int
+	msg  string
+}
+
+func (e *wordsError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// SplitArgs breaks a command string into arguments the way a POSIX
+// shell would, without performing any expansions.  Single quotes,
+// double quotes and backslash escapes are honored and an error is
+// returned for unterminated quotes.  This is useful for splitting
+// environment variables like `MYAPP_OPTS="--foo 'a b'"` before
+// calling one of the Process routines.
+func SplitArgs(s string) ([]string, error) {
+	words, err := splitWords(s)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, len(words))
+	for i, w := range words {
+		args[i] = w.text
+	}
+	return args, nil
+}
+
+// QuoteArgs joins args into a single string, quoting each argument as
+// needed so that SplitArgs or a POSIX shell will split it back into
+// the same arguments.
+func QuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	for _, c := range arg {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-+=@%:,./", c)) {
+			return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return arg
+}
+
+// splitWords breaks s into words using shell-like quoting rules:
+// whitespace separates words, single quotes preserve everything up
+// to the closing quote, double quotes allow backslash to escape
+// `"`, `\`, `$`, "`" and newline, and outside of quotes a backslash
+// escapes any character.  A `#` at the start of a word comments out
+// the rest of the line.  Each word records the line it started on.
+func splitWords(s string) ([]word, error) {
+	words := make([]word, 0)
+	var buf strings.Builder
+	inWord := false
+	line, start := 1, 1
+
+	begin := func() {
+		if !inWord {
+			inWord = true
+			start = line
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n' || c == ' ' || c == '\t' || c == '\r':
+			if inWord {
+				words = append(words, word{buf.String(), start})
+				buf.Reset()
+				inWord = false
+			}
+			if c == '\n' {
+				line++
+			}
+		case c == '#' && !inWord:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			if i < len(s) {
+				line++
+			}
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, &wordsError{line, "trailing backslash"}
+			}
+			i++
+			if s[i] == '\n' {
+				// line continuation
+				line++
+				continue
+			}
+			begin()
+			buf.WriteByte(s[i])
+		case c == '\'':
+			begin()
+			quoteLine := line
+			i++
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\n' {
+					line++
+				}
+				buf.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, &wordsError{quoteLine, "unterminated single quote"}
+			}
+		case c == '"':
+			begin()
+			quoteLine := line
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) != -1 {
+					i++
+					if s[i] == '\n' {
+						line++
+						continue
+					}
+				} else if s[i] == '\n' {
+					line++
+				}
+				buf.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, &wordsError{quoteLine, "unterminated double quote"}
+			}
+		default:
+			begin()
+			buf.WriteByte(c)
+		}
+	}
+	if inWord {
+		words = append(words, word{buf.String(), start})
+	}
+	return words, nil
+}
@@ -0,0 +1,41 @@
+package optigo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	words, err := splitWords("a 'b c'\n\"d \\\"e\\\"\" f\\ g # comment\nh\\\ni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []word{
+		{"a", 1},
+		{"b c", 1},
+		{`d "e"`, 2},
+		{"f g", 2},
+		{"hi", 3},
+	}
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("unexpected words: %#v", words)
+	}
+
+	if _, err := splitWords("a\n\"b"); err == nil || err.Error() != "line 2: unterminated double quote" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := SplitArgs(`--foo 'a b' --bar="c d" e\ f ''`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"--foo", "a b", "--bar=c d", "e f", ""}) {
+		t.Errorf("unexpected args: %#v", args)
+	}
+
+	if _, err := SplitArgs(`--foo 'a b`); err == nil {
+		t.Fail()
+	}
+}
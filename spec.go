@@ -0,0 +1,109 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Spec is an immutable, compiled set of options created with
+// OptionParser.Compile.  A Spec is safe for concurrent use and each
+// call to NewParser cheaply creates an independent OptionParser that
+// shares the compiled options.
+type Spec struct {
+	template OptionParser
+}
+
+// Compile captures the options, metadata, settings and the current
+// Results (as defaults) of the parser into an immutable Spec.  Parsers
+// with direct assignment destinations cannot be compiled since all
+// parsers created from the Spec would share the same destinations.
+func (o *OptionParser) Compile() (*Spec, error) {
+	for _, opt := range o.actions {
+		if opt.dest.Kind() == reflect.Ptr {
+			return nil, fmt.Errorf("cannot compile option --%s with a direct assignment destination", opt.name)
+		}
+	}
+	template := *o
+	template.actions = o.actions.copy()
+	template.shared = true
+	template.seen = nil
+	template.first = nil
+	template.files = nil
+	template.pending = nil
+	template.Args = nil
+	template.Occurrences = nil
+	if o.defaults != nil {
+		template.Results = copyResults(o.defaults)
+	} else {
+		template.Results = copyResults(o.Results)
+	}
+	template.defaults = template.Results
+	return &Spec{template}, nil
+}
+
+// NewParser creates a new OptionParser from the Spec.  The returned
+// parser can be used independently of any other parser created from
+// the same Spec.
+func (s *Spec) NewParser() OptionParser {
+	op := s.template
+	op.Results = copyResults(s.template.Results)
+	// state filled in by processing must never be shared
+	op.seen, op.first = nil, nil
+	op.files, op.pending = nil, nil
+	return op
+}
+
+// Reset restores the parser to its state before any arguments were
+// processed.  OptionParser.Results is restored to the values it held
+// before the first call to a Process routine and direct assignment
+// destinations are restored to the values they held when the parser
+// was created.  OptionParser.Args and OptionParser.Occurrences are
+// cleared, and files opened for =r and =w options are closed.
+func (o *OptionParser) Reset() {
+	if o.defaults != nil {
+		o.Results = copyResults(o.defaults)
+	}
+	for _, opt := range o.actions {
+		if opt.dflt.IsValid() {
+			opt.dest.Elem().Set(copyValue(opt.dflt))
+		}
+	}
+	o.closeFiles()
+	o.seen = nil
+	o.pending = nil
+	o.Args = nil
+	o.Occurrences = nil
+}
+
+func copyResults(results map[string]interface{}) map[string]interface{} {
+	if results == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(results))
+	for k, v := range results {
+		if v == nil {
+			c[k] = nil
+		} else {
+			c[k] = copyValue(reflect.ValueOf(v)).Interface()
+		}
+	}
+	return c
+}
@@ -0,0 +1,137 @@
+package optigo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	op := NewParser([]string{"v|verbose+", "t|tag=s@", "m|map=s%"})
+	op.Results["tag"] = []string{"default"}
+	op.Results["map"] = map[string]string{"a": "1"}
+
+	for i := 0; i < 2; i++ {
+		if err := op.ProcessAll([]string{"-v", "-v", "-t", "x", "-m", "b=2", "pos"}); err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{
+			"verbose": int64(2),
+			"tag":     []string{"default", "x"},
+			"map":     map[string]string{"a": "1", "b": "2"},
+		}
+		if !reflect.DeepEqual(op.Results, expected) {
+			t.Errorf("unexpected results on pass %d: %#v", i, op.Results)
+		}
+		op.Reset()
+	}
+
+	expected := map[string]interface{}{
+		"tag": []string{"default"},
+		"map": map[string]string{"a": "1"},
+	}
+	if !reflect.DeepEqual(op.Results, expected) || op.Args != nil || op.Occurrences != nil {
+		t.Errorf("unexpected state after reset: %#v %#v", op.Results, op.Args)
+	}
+}
+
+func TestResetDirectAssign(t *testing.T) {
+	count := int64(1)
+	tags := []string{"default"}
+	labels := map[string]string{"a": "1"}
+	op := NewDirectAssignParser(map[string]interface{}{
+		"v|verbose+": &count,
+		"t|tag=s@":   &tags,
+		"l|label=s%": &labels,
+	})
+
+	if err := op.ProcessAll([]string{"-v", "-t", "x", "-l", "b=2"}); err != nil {
+		t.Fatal(err)
+	}
+	op.Reset()
+
+	if count != 1 || !reflect.DeepEqual(tags, []string{"default"}) || !reflect.DeepEqual(labels, map[string]string{"a": "1"}) {
+		t.Errorf("unexpected values after reset: %v %v %v", count, tags, labels)
+	}
+}
+
+func TestSpecConcurrent(t *testing.T) {
+	op := NewParser([]string{"v|verbose+", "n|name=s", "t|tag=s@"})
+	op.Results["tag"] = []string{"default"}
+	spec, err := op.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := spec.NewParser()
+			p.Describe("name", OptionMeta{Usage: "private"})
+			name := fmt.Sprintf("n%d", i)
+			if err := p.ProcessAll([]string{"-v", "-n", name, "-t", name}); err != nil {
+				errs <- err
+				return
+			}
+			if p.Results["name"] != name || p.Results["verbose"] != int64(1) || !reflect.DeepEqual(p.Results["tag"], []string{"default", name}) {
+				errs <- fmt.Errorf("unexpected results: %#v", p.Results)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// describing options on parsers does not modify the spec
+	p := spec.NewParser()
+	for _, info := range p.Options() {
+		if info.Usage != "" {
+			t.Errorf("unexpected usage for %s: %s", info.Name, info.Usage)
+		}
+	}
+}
+
+func TestCompileDirectAssign(t *testing.T) {
+	var name string
+	op := NewDirectAssignParser(map[string]interface{}{
+		"n|name=s": &name,
+	})
+	if _, err := op.Compile(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestCompileFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	op := NewParser([]string{"i|input=r"})
+	if err := op.ProcessAll([]string{"-i", path}); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := op.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := spec.NewParser()
+	if err := p.ProcessAll([]string{"-i", path}); err != nil {
+		t.Fatal(err)
+	}
+	p.Reset()
+
+	// files opened by the compiled parser are its own
+	buf := make([]byte, 4)
+	if _, err := op.Results["input"].(*os.File).Read(buf); err != nil {
+		t.Errorf("original file closed by a parser from the spec: %s", err)
+	}
+	op.Reset()
+}
@@ -0,0 +1,230 @@
+/*
+ *
+ *  Copyright 2015 Netflix, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ *     you may not use this file except in compliance with the License.
+ *     You may obtain a copy of the License at
+ *
+ *         http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *     Unless required by applicable law or agreed to in writing, software
+ *     distributed under the License is distributed on an "AS IS" BASIS,
+ *     WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *     See the License for the specific language governing permissions and
+ *     limitations under the License.
+ *
+ */
+
+package optigo
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToArgs converts the parsed state back into arguments using the
+// canonical alias of each option.  Every value in OptionParser.Results
+// is emitted, and for direct assignment parsers every option that was
+// processed is emitted from its destination.  Values that lists and
+// maps already held before processing, from Results defaults or the
+// initial destination value, are left out.  Callback options are
+// skipped since they hold no state.  Processing the returned arguments
+// with a new parser built from the same specs and defaults yields the
+// same results.
+// Use QuoteArgs to turn the arguments into a single shell command string.
+func (o *OptionParser) ToArgs() []string {
+	args, tail := make([]string, 0), make([]string, 0)
+	for _, info := range o.Options() {
+		opt, _ := o.findOption(info.Name)
+		var val, base reflect.Value
+		if opt.dest.IsValid() {
+			if opt.dest.Kind() != reflect.Ptr || !o.seen[opt.name] {
+				continue
+			}
+			val, base = opt.dest.Elem(), opt.dflt
+		} else if v, ok := o.Results[opt.name]; ok {
+			val = reflect.ValueOf(v)
+			if d, ok := o.defaults[opt.name]; ok && d != nil {
+				base = reflect.ValueOf(d)
+			}
+		} else {
+			continue
+		}
+		if base.IsValid() && base.Type() != val.Type() {
+			base = reflect.Value{}
+		}
+		if opt.action == atCAPTURE && opt.term == "" {
+			// this swallows everything after it so must come last
+			tail = append(tail, opt.toArgs(val, base, o.MapSeparators)...)
+			continue
+		}
+		args = append(args, opt.toArgs(val, base, o.MapSeparators)...)
+	}
+	return append(args, tail...)
+}
+
+// toArgs returns the arguments for the value val of the option.  For
+// lists and maps, the values already held in base are skipped.  Map
+// keys are joined to their values as OptionParser.MapSeparators, given
+// in separators, expects.
+func (opt *option) toArgs(val, base reflect.Value, separators string) []string {
+	alias := opt.aliases[len(opt.aliases)-1]
+	sep := "="
+	if separators != "" {
+		sep = separators[:1]
+	}
+	args := make([]string, 0)
+	if len(opt.neg) > 0 && !opt.unary {
+		// clear any defaults so the values are reproduced exactly
+		args = append(args, opt.neg[0])
+		base = reflect.Value{}
+	}
+	switch opt.action {
+	case atINCREMENT:
+		if val.Int() == 0 {
+			break
+		}
+		if strings.HasPrefix(alias, "--") {
+			args = append(args, alias+"="+formatValue(val))
+		} else {
+			// short options take attached values as in -v3
+			args = append(args, alias+formatValue(val))
+		}
+	case atAPPEND:
+		for i := commonPrefix(val, base); i < val.Len(); i++ {
+			if opt.takesValues() {
+				args = append(append(args, alias), formatValues(val.Index(i))...)
+				continue
+			}
+			v := formatValue(val.Index(i))
+			if opt.listSep != 0 {
+				v = escapeListValue(v, opt.listSep)
+			}
+			args = append(args, alias, v)
+		}
+	case atMAP, atMAPLIST:
+		for _, k := range sortedKeys(val) {
+			v := val.MapIndex(k)
+			var old reflect.Value
+			if base.IsValid() {
+				old = base.MapIndex(k)
+			}
+			key := escapeMapKey(formatValue(k), separators) + sep
+			if opt.action == atMAP {
+				if !old.IsValid() || !reflect.DeepEqual(old.Interface(), v.Interface()) {
+					args = append(args, alias, key+formatValue(v))
+				}
+				continue
+			}
+			for i := commonPrefix(v, old); i < v.Len(); i++ {
+				args = append(args, alias, key+formatValue(v.Index(i)))
+			}
+		}
+	case atNESTED:
+		paths, leaves := flattenNested(val.Interface().(map[string]interface{}), string(opt.pathSep))
+		var oldLeaves map[string]interface{}
+		if base.IsValid() {
+			_, oldLeaves = flattenNested(base.Interface().(map[string]interface{}), string(opt.pathSep))
+		}
+		for _, path := range paths {
+			if old, ok := oldLeaves[path]; ok && reflect.DeepEqual(old, leaves[path]) {
+				continue
+			}
+			args = append(args, alias, escapeMapKey(path, separators)+sep+formatValue(reflect.ValueOf(leaves[path])))
+		}
+	case atCAPTURE:
+		args = append(append(args, alias), formatValues(val)...)
+		if opt.term != "" {
+			args = append(args, opt.term)
+		}
+	case atASSIGN:
+		if opt.unary {
+			if val.Bool() {
+				args = append(args, alias)
+			} else if len(opt.neg) > 0 {
+				args = append(args, opt.neg[0])
+			}
+		} else if opt.takesValues() {
+			args = append(append(args, alias), formatValues(val)...)
+		} else {
+			args = append(args, alias, formatValue(val))
+		}
+	}
+	return args
+}
+
+// commonPrefix returns how many of the leading elements of the slice
+// list are the elements of the slice base, or 0 when list does not
+// start with all of base.
+func commonPrefix(list, base reflect.Value) int {
+	if !base.IsValid() || base.Len() > list.Len() {
+		return 0
+	}
+	for i := 0; i < base.Len(); i++ {
+		if !reflect.DeepEqual(list.Index(i).Interface(), base.Index(i).Interface()) {
+			return 0
+		}
+	}
+	return base.Len()
+}
+
+// sortedKeys returns the keys of the map m, sorted numerically for
+// numeric keys and lexically otherwise.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case isSigned(a.Kind()):
+			return a.Int() < b.Int()
+		case isUnsigned(a.Kind()):
+			return a.Uint() < b.Uint()
+		case isFloat(a.Kind()):
+			return a.Float() < b.Float()
+		}
+		return formatValue(a) < formatValue(b)
+	})
+	return keys
+}
+
+// formatValues formats each element of the slice val.
+func formatValues(val reflect.Value) []string {
+	if val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	vals := make([]string, val.Len())
+	for i := range vals {
+		vals[i] = formatValue(val.Index(i))
+	}
+	return vals
+}
+
+func formatValue(val reflect.Value) string {
+	if val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	if f, ok := val.Interface().(*os.File); ok {
+		if f == os.Stdin || f == os.Stdout {
+			return "-"
+		}
+		return f.Name()
+	}
+	switch val.Kind() {
+	case reflect.String:
+		return val.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(val.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'g', -1, 64)
+	}
+	return fmt.Sprint(val.Interface())
+}
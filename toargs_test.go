@@ -0,0 +1,122 @@
+package optigo
+
+import (
+	"reflect"
+	"testing"
+)
+
+var toArgsSpecs = []string{
+	"v|verbose+",
+	"b|bool",
+	"q|quiet",
+	"s|str=s",
+	"i|int=i",
+	"f|float=f",
+	"S|strs=s@",
+	"F|floats=f@",
+	"m|map=s%",
+	"n|nums=i%",
+	"L|lists=s%@",
+	"u|unset=s",
+}
+
+func TestToArgs(t *testing.T) {
+	op := NewParser(toArgsSpecs)
+	args := []string{
+		"-v", "-v", "-b", "-s", "it's a test", "-i", "-42", "-f", "0.1",
+		"-S", "a", "-S", "", "-F", "1e100", "-m", "b=2", "-m", "a=1 2", "-n", "x=3",
+		"-L", "k=2", "-L", "k=1",
+	}
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"--bool", "--float", "0.1", "--floats", "1e+100", "--int", "-42", "--lists", "k=2", "--lists", "k=1",
+		"--map", "a=1 2", "--map", "b=2", "--nums", "x=3",
+		"--str", "it's a test", "--strs", "a", "--strs", "", "--verbose=2",
+	}
+	got := op.ToArgs()
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected args:\n%#v\n%#v", got, expected)
+	}
+
+	// round trip through a quoted string and a new parser
+	reparsed := NewParser(toArgsSpecs)
+	if err := reparsed.ProcessString(QuoteArgs(got)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reparsed.Results, op.Results) {
+		t.Errorf("unexpected results:\n%#v\n%#v", reparsed.Results, op.Results)
+	}
+}
+
+func TestToArgsDirectAssign(t *testing.T) {
+	var count int64
+	var floats []float32
+	var name string
+	op := NewDirectAssignParser(map[string]interface{}{
+		"v|verbose+": &count,
+		"floats=f@":  &floats,
+		"name=s":     &name,
+		"h|help":     func() {},
+	})
+	if err := op.ProcessAll([]string{"-v", "--floats", "0.1", "-h"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--floats", "0.1", "--verbose=1"}
+	if got := op.ToArgs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected args: %#v", got)
+	}
+}
+
+func TestToArgsDefaults(t *testing.T) {
+	names := []string{"a"}
+	sizes := map[string]int{"x": 1, "y": 2}
+	op := NewDirectAssignParser(map[string]interface{}{
+		"names=s@": &names,
+		"sizes=i%": &sizes,
+	})
+	if err := op.ProcessAll([]string{"--names", "b", "--sizes", "y=3", "--sizes", "z=4"}); err != nil {
+		t.Fatal(err)
+	}
+	args := op.ToArgs()
+	expected := []string{"--names", "b", "--sizes", "y=3", "--sizes", "z=4"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected args: %#v", args)
+	}
+	names2 := []string{"a"}
+	sizes2 := map[string]int{"x": 1, "y": 2}
+	op = NewDirectAssignParser(map[string]interface{}{
+		"names=s@": &names2,
+		"sizes=i%": &sizes2,
+	})
+	if err := op.ProcessAll(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names2, names) || !reflect.DeepEqual(sizes2, sizes) {
+		t.Errorf("round trip gave %v %v, expected %v %v", names2, sizes2, names, sizes)
+	}
+
+	op = NewParser([]string{"L|lists=s%@"})
+	op.Results = map[string]interface{}{"lists": map[string][]string{"k": {"a"}}}
+	if err := op.ProcessAll([]string{"-L", "k=b", "-L", "j=c"}); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"--lists", "j=c", "--lists", "k=b"}
+	if got := op.ToArgs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected args: %#v", got)
+	}
+}
+
+func TestQuoteArgs(t *testing.T) {
+	args := []string{"plain", "", "a b", "it's", "#x", `"q"`, "--k=v"}
+	quoted := QuoteArgs(args)
+	if quoted != `plain '' 'a b' 'it'\''s' '#x' '"q"' --k=v` {
+		t.Errorf("unexpected quoting: %s", quoted)
+	}
+	split, err := SplitArgs(quoted)
+	if err != nil || !reflect.DeepEqual(split, args) {
+		t.Errorf("unexpected split: %#v %v", split, err)
+	}
+}